@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"holang/pkg/printer"
+)
+
+var astFlags struct {
+	lower *bool
+}
+
+var astCmd = &command{
+	name:  "ast",
+	args:  "[flags] [file]",
+	short: "dump the syntax tree of a file",
+	long: `
+Ast parses a holang file and dumps its Go syntax tree, in which the holang
+constructs are lowered as far as the parser can on its own.`,
+	flags: func(fs *flag.FlagSet) {
+		astFlags.lower = fs.Bool("lower", false, "also lower the constructs that need type information")
+	},
+	run: runAST,
+}
+
+func runAST(fs *flag.FlagSet) int {
+	s, code, ok := singleSource(fs)
+	if !ok {
+		return code
+	}
+	u, diags := compile(s, *astFlags.lower)
+	if code := report(s, diags); code != exitOK {
+		return code
+	}
+	if err := printer.DumpFile(os.Stdout, u.file); err != nil {
+		fmt.Fprintf(os.Stderr, "ho ast: %v\n", err)
+		return exitErrors
+	}
+	return exitOK
+}
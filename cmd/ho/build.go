@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"holang/pkg/diagnostics"
+	"holang/pkg/driver"
+)
+
+var buildFlags struct {
+	output *string
+}
+
+var buildCmd = &command{
+	name:  "build",
+	args:  "[flags] [dir | file.ho]",
+	short: "compile a package to an executable",
+	long: `
+Build compiles the package in dir, the current directory by default, with
+the go command: its holang files are translated to Go alongside its Go
+files. Within a module, the holang files of the whole module are
+translated, so that packages import each other whatever their language.
+Given a single holang file, build compiles it alone.
+
+The executable is named after the directory or the file, unless -o is
+given. The errors of the go command point at the holang files.`,
+	flags: func(fs *flag.FlagSet) {
+		buildFlags.output = fs.String("o", "", "write the executable to `file`")
+	},
+	run: runBuild,
+}
+
+var runCmd = &command{
+	name:  "run",
+	args:  "[dir | file.ho] [arguments]",
+	short: "compile and run a package",
+	long: `
+Run compiles the package in dir, or the holang file, like build does, then
+runs it with the arguments and exits with its exit status. With no
+argument, it runs the package in the current directory.`,
+	run: runRun,
+}
+
+func runBuild(fs *flag.FlagSet) int {
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	path := "."
+	if fs.NArg() == 1 {
+		path = fs.Arg(0)
+	}
+	output := *buildFlags.output
+	if output == "" {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ho build: %v\n", err)
+			return exitErrors
+		}
+		output = strings.TrimSuffix(filepath.Base(abs), driver.Ext)
+		if runtime.GOOS == "windows" {
+			output += ".exe"
+		}
+	}
+	output, err := filepath.Abs(output)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ho build: %v\n", err)
+		return exitErrors
+	}
+	return build(path, output)
+}
+
+func runRun(fs *flag.FlagSet) int {
+	args := fs.Args()
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	dir, err := ioutil.TempDir("", "ho-run")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ho run: %v\n", err)
+		return exitErrors
+	}
+	defer os.RemoveAll(dir)
+	executable := filepath.Join(dir, "main")
+	if code := build(args[0], executable); code != exitOK {
+		return code
+	}
+
+	cmd := exec.Command(executable, args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		if exit, ok := err.(*exec.ExitError); ok && exit.ExitCode() >= 0 {
+			return exit.ExitCode()
+		}
+		fmt.Fprintf(os.Stderr, "ho run: %v\n", err)
+		return exitErrors
+	}
+	return exitOK
+}
+
+// build compiles the package or the holang file at path to the executable
+// at the absolute path output.
+func build(path, output string) int {
+	b, err := driver.New(path)
+	if list, ok := err.(diagnostics.List); ok {
+		return reportAll(b.Sources, list)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "ho: %v\n", err)
+		return exitErrors
+	}
+	defer b.Close()
+
+	var stderr bytes.Buffer
+	cmd := b.Command("build", "-o", output)
+	cmd.Stdout, cmd.Stderr = os.Stdout, &stderr
+	err = cmd.Run()
+	os.Stderr.Write(b.MapPositions(stderr.Bytes()))
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); !ok {
+			fmt.Fprintf(os.Stderr, "ho: %v\n", err)
+		}
+		return exitErrors
+	}
+	return exitOK
+}
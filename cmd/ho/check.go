@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	goast "go/ast"
+	gotoken "go/token"
+	"io/ioutil"
+	"os"
+
+	"holang/pkg/check"
+	"holang/pkg/driver"
+)
+
+var checkCmd = &command{
+	name:  "check",
+	args:  "[dir | file]",
+	short: "report the errors of a package or a file",
+	long: `
+Check parses, lowers and type checks a holang file, or the package in dir
+with its Go files, without emitting anything, and reports its errors. It
+exits with status 1 if there are any.
+
+A file is type checked on its own: the other files of its package are not
+considered.`,
+	run: runCheck,
+}
+
+func runCheck(fs *flag.FlagSet) int {
+	if fs.NArg() == 1 {
+		if info, err := os.Stat(fs.Arg(0)); err == nil && info.IsDir() {
+			return checkDir(fs.Arg(0))
+		}
+	}
+	s, code, ok := singleSource(fs)
+	if !ok {
+		return code
+	}
+	u, diags := compile(s, true)
+	if diags.Err() == nil {
+		_, _, errs := check.Files(u.fset, u.file.Name.Name, []*goast.File{&u.file}, u.imports)
+		diags = append(diags, errs...)
+	}
+	return report(s, diags)
+}
+
+// checkDir checks the package in dir.
+func checkDir(dir string) int {
+	pkg, err := driver.LoadDir(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ho check: %v\n", err)
+		return exitErrors
+	}
+	fset := gotoken.NewFileSet()
+	imports := driver.NewImporter()
+	files, diags := driver.LoadFiles(fset, pkg.Dir, imports)
+	if diags.Err() == nil && len(files) > 0 {
+		_, _, errs := check.Files(fset, files[0].Name.Name, files, imports)
+		diags = append(diags, errs...)
+	}
+	return reportAll(sources(fset), diags)
+}
+
+// sources returns the contents of the files of fset, by name, for
+// rendering diagnostics.
+func sources(fset *gotoken.FileSet) map[string][]byte {
+	m := map[string][]byte{}
+	fset.Iterate(func(f *gotoken.File) bool {
+		if src, err := ioutil.ReadFile(f.Name()); err == nil {
+			m[f.Name()] = src
+		}
+		return true
+	})
+	return m
+}
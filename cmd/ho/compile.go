@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	goast "go/ast"
+	gotoken "go/token"
+	"io/ioutil"
+	"os"
+
+	"holang/pkg/check"
+	"holang/pkg/diagnostics"
+	"holang/pkg/driver"
+)
+
+// stdin is the name of the standard input in diagnostics.
+const stdin = "<stdin>"
+
+// source is a holang file read by a command.
+type source struct {
+	path string
+	src  []byte
+}
+
+// readSource reads the file named by args, the standard input if there is
+// none or it is "-".
+func readSource(args []string) (source, error) {
+	if len(args) == 0 || args[0] == "-" {
+		src, err := ioutil.ReadAll(os.Stdin)
+		return source{path: stdin, src: src}, err
+	}
+	src, err := ioutil.ReadFile(args[0])
+	return source{path: args[0], src: src}, err
+}
+
+// singleSource reads the file named by the arguments of a command taking at
+// most one, reporting the errors. ok is false if the command must exit with
+// code.
+func singleSource(fs *flag.FlagSet) (s source, code int, ok bool) {
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return source{}, exitUsage, false
+	}
+	s, err := readSource(fs.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ho %s: %v\n", fs.Name(), err)
+		return source{}, exitErrors, false
+	}
+	return s, exitOK, true
+}
+
+// unit is a parsed holang file.
+type unit struct {
+	fset    *gotoken.FileSet
+	file    goast.File
+	imports *check.Importer
+}
+
+// compile parses s and, if it has no syntax errors and lowering is asked
+// for, lowers its holang constructs. It returns every diagnostic found.
+func compile(s source, lowering bool) (*unit, diagnostics.List) {
+	u := &unit{fset: gotoken.NewFileSet(), imports: driver.NewImporter()}
+	var diags diagnostics.List
+	if lowering {
+		u.file, diags = driver.Lower(u.fset, s.path, s.src, u.imports)
+	} else {
+		u.file, _, diags = driver.Parse(u.fset, s.path, s.src)
+	}
+	return u, diags
+}
+
+// report renders diags to the standard error and returns the exit code of
+// a command that found them.
+func report(s source, diags diagnostics.List) int {
+	return reportAll(map[string][]byte{s.path: s.src}, diags)
+}
+
+// reportAll is like report for diagnostics over the files of sources, by
+// path.
+func reportAll(sources map[string][]byte, diags diagnostics.List) int {
+	if len(diags) == 0 {
+		return exitOK
+	}
+	r := diagnostics.Renderer{Sources: sources}
+	r.RenderAll(os.Stderr, diags)
+	if diags.Err() != nil {
+		return exitErrors
+	}
+	return exitOK
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	gobuild "go/build"
+	"os"
+	"path/filepath"
+
+	"holang/pkg/conformance"
+)
+
+var conformanceFlags struct {
+	positions, verbose *bool
+}
+
+var conformanceCmd = &command{
+	name:  "conformance",
+	args:  "[flags] [dir]",
+	short: "report how much of Go the parser accepts",
+	long: `
+Conformance parses every Go file of the tree rooted at dir, $GOROOT/src by
+default, with both the holang parser and go/parser, and compares the syntax
+trees. It prints the number of files on which the parsers agree and the pass
+rate of every directory, then of the whole tree. Files go/parser rejects and
+test data are left out.
+
+With -pos, the positions of the nodes are compared too. With -v, the first
+difference of every file on which the parsers disagree is printed under its
+directory.`,
+	flags: func(fs *flag.FlagSet) {
+		conformanceFlags.positions = fs.Bool("pos", false, "compare the positions of the nodes")
+		conformanceFlags.verbose = fs.Bool("v", false, "print the first difference of every failing file")
+	},
+	run: runConformance,
+}
+
+func runConformance(fs *flag.FlagSet) int {
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	root := filepath.Join(gobuild.Default.GOROOT, "src")
+	if fs.NArg() == 1 {
+		root = fs.Arg(0)
+	}
+	var mode conformance.Mode
+	if *conformanceFlags.positions {
+		mode |= conformance.Positions
+	}
+	pkgs, err := conformance.Dir(root, mode)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ho conformance: %v\n", err)
+		return exitErrors
+	}
+
+	total := conformance.Total(pkgs)
+	counts := func(pkg *conformance.Package) string {
+		return fmt.Sprintf("%d/%d", pkg.Passed(), pkg.Files)
+	}
+	width := len(counts(total))
+	for _, pkg := range pkgs {
+		fmt.Printf("%*s %6.1f%%  %s\n", width, counts(pkg), 100*pkg.Rate(), pkg.Dir)
+		if *conformanceFlags.verbose {
+			for _, f := range pkg.Failures {
+				fmt.Printf("\t%v\n", f.Err)
+			}
+		}
+	}
+	fmt.Printf("%*s %6.1f%%  total\n", width, counts(total), 100*total.Rate())
+	return exitOK
+}
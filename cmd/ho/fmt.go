@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"holang/pkg/diagnostics"
+	"holang/pkg/format"
+)
+
+var fmtFlags struct {
+	list, write *bool
+}
+
+var fmtCmd = &command{
+	name:  "fmt",
+	args:  "[flags] [files]",
+	short: "format holang source",
+	long: `
+Fmt formats holang files, printing the result to the standard output. With
+no file, or "-", it formats the standard input. Files with syntax errors are
+reported and left untouched.`,
+	flags: func(fs *flag.FlagSet) {
+		fmtFlags.list = fs.Bool("l", false, "list the files whose formatting differs")
+		fmtFlags.write = fs.Bool("w", false, "write the result to the files instead of the standard output")
+	},
+	run: runFmt,
+}
+
+func runFmt(fs *flag.FlagSet) int {
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	code := exitOK
+	for _, path := range paths {
+		if c := fmtFile(path); c > code {
+			code = c
+		}
+	}
+	return code
+}
+
+func fmtFile(path string) int {
+	s, err := readSource([]string{path})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ho fmt: %v\n", err)
+		return exitErrors
+	}
+	out, err := format.Source(s.src, s.path)
+	if err != nil {
+		if list, ok := err.(diagnostics.List); ok {
+			return report(s, list)
+		}
+		fmt.Fprintf(os.Stderr, "ho fmt: %v\n", err)
+		return exitErrors
+	}
+
+	changed := !bytes.Equal(s.src, out)
+	if *fmtFlags.list && changed {
+		fmt.Println(s.path)
+	}
+	if *fmtFlags.write && s.path != stdin {
+		if changed {
+			if err := ioutil.WriteFile(s.path, out, 0666); err != nil {
+				fmt.Fprintf(os.Stderr, "ho fmt: %v\n", err)
+				return exitErrors
+			}
+		}
+		return exitOK
+	}
+	if !*fmtFlags.list {
+		os.Stdout.Write(out)
+	}
+	return exitOK
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"holang/pkg/driver"
+	"holang/pkg/printer"
+)
+
+var genFlags struct {
+	output *string
+	line   *bool
+}
+
+var genCmd = &command{
+	name:  "gen",
+	args:  "[flags] [file]",
+	short: "translate a file to Go",
+	long: `
+Gen translates a holang file to gofmt-formatted Go source, written to the
+standard output unless -o is given.
+
+With -line, the Go source carries //line directives pointing at the holang
+file, relative to the directory of the Go file, so that the go command,
+stack traces and debuggers report positions in the holang file.`,
+	flags: func(fs *flag.FlagSet) {
+		genFlags.output = fs.String("o", "", "write the Go source to `file`")
+		genFlags.line = fs.Bool("line", false, "emit //line directives pointing at the holang file")
+	},
+	run: runGen,
+}
+
+func runGen(fs *flag.FlagSet) int {
+	s, code, ok := singleSource(fs)
+	if !ok {
+		return code
+	}
+	config := printer.Config{LineDirectives: *genFlags.line}
+	if *genFlags.output != "" && s.path != stdin {
+		// Relative names in directives are relative to the Go file
+		config.Filename = filepath.Base(*genFlags.output)
+		if rel, err := relativeTo(*genFlags.output, s.path); err == nil {
+			config.SourceName = rel
+		}
+	}
+	gen, _, diags := driver.Translate(s.path, s.src, config, driver.NewImporter())
+	if code := report(s, diags); code != exitOK {
+		return code
+	}
+	if *genFlags.output == "" {
+		os.Stdout.Write(gen)
+		return exitOK
+	}
+	if err := ioutil.WriteFile(*genFlags.output, gen, 0666); err != nil {
+		fmt.Fprintf(os.Stderr, "ho gen: %v\n", err)
+		return exitErrors
+	}
+	return exitOK
+}
+
+// relativeTo returns the path of the file at path relative to the directory
+// of the file at from.
+func relativeTo(from, path string) (string, error) {
+	from, err := filepath.Abs(from)
+	if err != nil {
+		return "", err
+	}
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(filepath.Dir(from), path)
+}
@@ -1,35 +1,127 @@
+// Command ho works with holang source files: it formats them, translates
+// them to Go and builds or runs the resulting programs.
+//
+// Usage:
+//
+//	ho <command> [arguments]
+//
+// Commands reading a single file read the standard input when the file is
+// omitted or is "-". Run "ho help <command>" for the details of a command.
 package main
 
 import (
-	"errors"
-	"log"
+	"flag"
+	"fmt"
+	"io"
 	"os"
+	"strings"
+)
 
-	"holang/pkg/lexer"
-	"holang/pkg/parser"
-	"holang/pkg/printer"
+// Exit codes
+const (
+	exitOK     = 0
+	exitErrors = 1 // the source has errors, or a tool failed
+	exitUsage  = 2
 )
 
+type command struct {
+	name  string
+	args  string // synopsis of the arguments
+	short string // one-line description
+	long  string // description, after the synopsis in the help text
+	flags func(fs *flag.FlagSet)
+	run   func(fs *flag.FlagSet) int
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{tokensCmd, astCmd, fmtCmd, genCmd, checkCmd, buildCmd, runCmd, conformanceCmd}
+}
+
 func main() {
-	if len(os.Args) != 2 {
-		panic(errors.New("Usage: holang <filepath>"))
+	os.Exit(dispatch(os.Args[1:]))
+}
+
+func dispatch(args []string) int {
+	if len(args) == 0 {
+		usage(os.Stderr)
+		return exitUsage
+	}
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		return help(args[1:])
 	}
-	filepath := os.Args[1]
-	reader, err := os.Open(filepath)
-	if err != nil {
-		panic(err)
+	cmd := lookup(args[0])
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "ho: unknown command %q\nRun 'ho help' for usage.\n", args[0])
+		return exitUsage
 	}
+	fs := newFlagSet(cmd, os.Stderr)
+	if err := fs.Parse(args[1:]); err != nil {
+		return exitUsage
+	}
+	return cmd.run(fs)
+}
 
-	tokens, err := lexer.Tokenize(reader, filepath)
-	if err != nil {
-		log.Fatal(err)
+func lookup(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
 	}
+	return nil
+}
+
+func newFlagSet(cmd *command, w io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(w)
+	fs.Usage = func() { commandUsage(w, cmd, fs) }
+	if cmd.flags != nil {
+		cmd.flags(fs)
+	}
+	return fs
+}
+
+func help(args []string) int {
+	switch len(args) {
+	case 0:
+		usage(os.Stdout)
+		return exitOK
+	case 1:
+		cmd := lookup(args[0])
+		if cmd == nil {
+			fmt.Fprintf(os.Stderr, "ho help: unknown command %q\n", args[0])
+			return exitUsage
+		}
+		commandUsage(os.Stdout, cmd, newFlagSet(cmd, os.Stdout))
+		return exitOK
+	}
+	fmt.Fprintln(os.Stderr, "usage: ho help [command]")
+	return exitUsage
+}
 
-	p := parser.NewParser(tokens)
-	file, err := p.ParseFile()
-	if err != nil {
-		log.Fatal(err)
+func usage(w io.Writer) {
+	fmt.Fprint(w, "ho works with holang source files.\n\nUsage:\n\n\tho <command> [arguments]\n\nThe commands are:\n\n")
+	width := 0
+	for _, cmd := range commands {
+		if len(cmd.name) > width {
+			width = len(cmd.name)
+		}
+	}
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "\t%-*s  %s\n", width, cmd.name, cmd.short)
 	}
+	fmt.Fprint(w, "\nRun 'ho help <command>' for more information about a command.\n")
+}
 
-	printer.FprintFile(os.Stdout, file)
+func commandUsage(w io.Writer, cmd *command, fs *flag.FlagSet) {
+	fmt.Fprintf(w, "usage: ho %s %s\n\n%s\n", cmd.name, cmd.args, strings.TrimSpace(cmd.long))
+	hasFlags := false
+	fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		fmt.Fprint(w, "\nFlags:\n")
+		fs.SetOutput(w)
+		fs.PrintDefaults()
+	}
 }
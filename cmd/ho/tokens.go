@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	gotoken "go/token"
+	"os"
+
+	"holang/pkg/diagnostics"
+	"holang/pkg/lexer"
+	hotoken "holang/pkg/token"
+)
+
+var tokensFlags struct {
+	comments, asi *bool
+}
+
+var tokensCmd = &command{
+	name:  "tokens",
+	args:  "[flags] [file]",
+	short: "print the token stream of a file",
+	long: `
+Tokens prints the tokens of a holang file, one per line: its position, its
+type and its quoted value. Illegal tokens are printed too, and reported
+after the stream.`,
+	flags: func(fs *flag.FlagSet) {
+		tokensFlags.comments = fs.Bool("comments", true, "print comments")
+		tokensFlags.asi = fs.Bool("asi", true, "print the semicolons inserted at the end of lines")
+	},
+	run: runTokens,
+}
+
+func runTokens(fs *flag.FlagSet) int {
+	s, code, ok := singleSource(fs)
+	if !ok {
+		return code
+	}
+
+	tokens, err := lexer.Tokenize(nil, bytes.NewReader(s.src), s.path)
+	w := bufio.NewWriter(os.Stdout)
+	for _, t := range tokens {
+		if t.Type == gotoken.COMMENT && !*tokensFlags.comments ||
+			t.Type == gotoken.SEMICOLON && t.Value == "\n" && !*tokensFlags.asi {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%q\n", t.Position, hotoken.String(t.Type), t.Value)
+	}
+	w.Flush()
+	list, _ := err.(diagnostics.List)
+	return report(s, list)
+}
@@ -1,8 +1,5 @@
 module holang
 
-go 1.12
+go 1.16
 
-require (
-	github.com/davecgh/go-spew v1.1.1
-	github.com/gobuffalo/plush v3.8.0+incompatible
-)
+require github.com/gobuffalo/plush v3.8.0+incompatible
@@ -0,0 +1,62 @@
+// Package astutil holds the helpers over Go syntax trees shared by the
+// passes generating code.
+package astutil
+
+import (
+	goast "go/ast"
+	gotoken "go/token"
+	"strconv"
+	"strings"
+)
+
+// Import returns the name under which the package at path can be referred
+// to by code generated in a file importing imports. The spec returned is
+// the import the file must be given, nil if one of imports already makes
+// the package available.
+func Import(imports []*goast.ImportSpec, path string) (*goast.Ident, *goast.ImportSpec) {
+	quoted := strconv.Quote(path)
+	name := path[strings.LastIndex(path, "/")+1:]
+	for _, spec := range imports {
+		if spec.Path.Value != quoted {
+			continue
+		}
+		if spec.Name == nil {
+			return goast.NewIdent(name), nil
+		}
+		if spec.Name.Name != "_" && spec.Name.Name != "." {
+			return goast.NewIdent(spec.Name.Name), nil
+		}
+	}
+	return goast.NewIdent(name), &goast.ImportSpec{Path: &goast.BasicLit{Kind: gotoken.STRING, Value: quoted}}
+}
+
+// AddImport adds spec to the imports of file: to its last import
+// declaration, grouping the imports it has, or to a new declaration
+// following the package clause if there is none. The import of "C" is left
+// alone, its declaration holding the preamble of cgo.
+func AddImport(file *goast.File, spec *goast.ImportSpec) {
+	var last *goast.GenDecl
+	imported := 0
+	for ; imported < len(file.Decls); imported++ {
+		decl, ok := file.Decls[imported].(*goast.GenDecl)
+		if !ok || decl.Tok != gotoken.IMPORT {
+			break
+		}
+		if len(decl.Specs) != 1 || decl.Specs[0].(*goast.ImportSpec).Path.Value != `"C"` {
+			last = decl
+		}
+	}
+	file.Imports = append(file.Imports, spec)
+	if last == nil {
+		decl := &goast.GenDecl{Tok: gotoken.IMPORT, Specs: []goast.Spec{spec}}
+		decls := append([]goast.Decl{}, file.Decls[:imported]...)
+		file.Decls = append(append(decls, decl), file.Decls[imported:]...)
+		return
+	}
+	if !last.Lparen.IsValid() {
+		// Grouped on the lines of the import
+		last.Lparen = last.Specs[0].Pos()
+		last.Rparen = last.Specs[0].End()
+	}
+	last.Specs = append(last.Specs, spec)
+}
@@ -0,0 +1,71 @@
+// Package check type checks holang files with go/types, once lowered to Go
+// syntax trees, and reports the type errors at their holang positions: the
+// positions of the trees are those of the holang tokens.
+//
+// The types.Info gathered lets the holang passes ask for the types of
+// expressions.
+package check
+
+import (
+	goast "go/ast"
+	gotoken "go/token"
+	"go/types"
+
+	"holang/pkg/diagnostics"
+)
+
+// NewInfo returns a types.Info recording every kind of information.
+func NewInfo() *types.Info {
+	return &types.Info{
+		Types:      map[goast.Expr]types.TypeAndValue{},
+		Defs:       map[*goast.Ident]types.Object{},
+		Uses:       map[*goast.Ident]types.Object{},
+		Implicits:  map[goast.Node]types.Object{},
+		Selections: map[*goast.SelectorExpr]*types.Selection{},
+		Scopes:     map[goast.Node]*types.Scope{},
+	}
+}
+
+// Files type checks files, the files of the package at path, importing
+// packages with importer, and returns the package and the information
+// gathered along with a diagnostics.List of every type error. Type checking
+// goes on after errors, so the package and the information are partial if
+// there are any.
+func Files(fset *gotoken.FileSet, path string, files []*goast.File, importer types.Importer) (*types.Package, *types.Info, diagnostics.List) {
+	info := NewInfo()
+	diags := diagnostics.List{}
+	conf := types.Config{
+		Importer: importer,
+		Error: func(err error) {
+			if err, ok := err.(types.Error); ok {
+				diags = append(diags, newDiagnostic(fset, files, err))
+			}
+		},
+	}
+	pkg, _ := conf.Check(path, fset, files, info)
+	diags.Sort()
+	return pkg, info, diags
+}
+
+// span returns the span of the smallest node of files starting at pos: the
+// token the type checker points at.
+func span(fset *gotoken.FileSet, files []*goast.File, pos gotoken.Pos) diagnostics.Span {
+	end := gotoken.NoPos
+	for _, file := range files {
+		goast.Inspect(file, func(node goast.Node) bool {
+			// Nodes generated by lowering have no position, which makes the
+			// ends of their ancestors unreliable
+			if node == nil || node.Pos() > pos {
+				return false
+			}
+			if node.Pos() == pos && node.End() > pos && (end == gotoken.NoPos || node.End() < end) {
+				end = node.End()
+			}
+			return true
+		})
+	}
+	if end == gotoken.NoPos {
+		end = pos
+	}
+	return diagnostics.Span{Start: position(fset, pos), End: position(fset, end)}
+}
@@ -0,0 +1,74 @@
+package check_test
+
+import (
+	"fmt"
+	gotoken "go/token"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"holang/pkg/check"
+	"holang/pkg/driver"
+	"holang/pkg/internal/testdir"
+)
+
+// TestFiles checks that the files of a package are type checked together,
+// holang and Go ones, the holang packages they import included, and that
+// errors are reported at their positions in the files of the package.
+func TestFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+	}{
+		{
+			"holang files",
+			map[string]string{
+				"a.ho": "package p\n\nfunc half(n int) int { return n / 2 }\n",
+				"b.ho": "package p\n\nvar s string = half(2 ** 3)\n",
+			},
+			[]string{"b.ho:3:16"},
+		},
+		{
+			"Go files",
+			map[string]string{
+				"a.ho": "package p\n\nvar n = 2 ** 3\n",
+				"b.go": "package p\n\nvar s string = n\n",
+			},
+			[]string{"b.go:3:16"},
+		},
+		{
+			"holang imports",
+			map[string]string{
+				"go.mod":   "module example.com/m\n\ngo 1.16\n",
+				"lib/a.ho": "package lib\n\nfunc Square(n int) int { return n ** 2 }\n",
+				"main.ho":  "package main\n\nimport \"example.com/m/lib\"\n\nvar s string = lib.Square(2)\n\nfunc main() {}\n",
+			},
+			[]string{"main.ho:5:16"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := testdir.Write(t, test.files)
+			fset := gotoken.NewFileSet()
+			imports := driver.NewImporter()
+			files, diags := driver.LoadFiles(fset, dir, imports)
+			if err := diags.Err(); err != nil {
+				t.Fatal(err)
+			}
+			_, _, diags = check.Files(fset, files[0].Name.Name, files, imports)
+			got := []string{}
+			for _, d := range diags {
+				if d.Code != check.TypeError {
+					t.Errorf("got code %s, want %s", d.Code, check.TypeError)
+				}
+				start := d.Primary.Start
+				rel, _ := filepath.Rel(dir, start.Filename)
+				got = append(got, fmt.Sprintf("%s:%d:%d", filepath.ToSlash(rel), start.Line, start.Column))
+			}
+			if strings.Join(got, " ") != strings.Join(test.want, " ") {
+				t.Errorf("got errors at %v, want %v\n%v", got, test.want, diags.Err())
+			}
+		})
+	}
+}
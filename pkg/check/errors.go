@@ -0,0 +1,33 @@
+package check
+
+import (
+	goast "go/ast"
+	gotoken "go/token"
+	"go/types"
+	"text/scanner"
+
+	"holang/pkg/diagnostics"
+)
+
+// Diagnostic codes of the type checker
+const (
+	TypeError = "C0001"
+)
+
+func newDiagnostic(fset *gotoken.FileSet, files []*goast.File, err types.Error) *diagnostics.Diagnostic {
+	d := &diagnostics.Diagnostic{
+		Severity: diagnostics.Error,
+		Code:     TypeError,
+		Message:  err.Msg,
+	}
+	if err.Pos.IsValid() {
+		d.Primary = span(fset, files, err.Pos)
+	}
+	return d
+}
+
+// position converts pos to the representation used by diagnostics.
+func position(fset *gotoken.FileSet, pos gotoken.Pos) scanner.Position {
+	p := fset.Position(pos)
+	return scanner.Position{Filename: p.Filename, Offset: p.Offset, Line: p.Line, Column: p.Column}
+}
@@ -0,0 +1,142 @@
+package check
+
+import (
+	"bufio"
+	"fmt"
+	goast "go/ast"
+	"go/importer"
+	gotoken "go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"holang/pkg/diagnostics"
+	"holang/pkg/parser"
+)
+
+// Importer imports packages for type checking: the packages of the main
+// module holding holang files from their sources, through Load, and the
+// other packages from their sources with go/importer.
+type Importer struct {
+	// Load returns the syntax trees of the files of the package in dir,
+	// those of the holang files lowered, and the enums they declare, with
+	// the diagnostics of their errors. Packages holding holang files are
+	// imported like Go ones if Load is nil.
+	Load func(fset *gotoken.FileSet, dir string) ([]*goast.File, []*parser.Enum, diagnostics.List)
+
+	fset     *gotoken.FileSet
+	fallback types.ImporterFrom
+	packages map[string]*types.Package
+	enums    map[string][]*parser.Enum // enums of the holang packages imported, by path
+	loading  map[string]bool           // holang packages being imported
+}
+
+// NewImporter returns an Importer without Load.
+func NewImporter() *Importer {
+	fset := gotoken.NewFileSet()
+	return &Importer{
+		fset:     fset,
+		fallback: importer.ForCompiler(fset, "source", nil).(types.ImporterFrom),
+		packages: map[string]*types.Package{},
+		enums:    map[string][]*parser.Enum{},
+		loading:  map[string]bool{},
+	}
+}
+
+// Import imports the package at path, relative to the current directory.
+func (imp *Importer) Import(path string) (*types.Package, error) {
+	return imp.ImportFrom(path, "", 0)
+}
+
+// ImportFrom imports the package at path, as imported by a file in dir.
+func (imp *Importer) ImportFrom(path, dir string, mode types.ImportMode) (*types.Package, error) {
+	if pkg, ok := imp.packages[path]; ok {
+		return pkg, nil
+	}
+	pkgDir, ok := moduleDir(path, dir)
+	if imp.Load == nil || !ok || !holdsHolang(pkgDir) {
+		return imp.fallback.ImportFrom(path, dir, mode)
+	}
+	if imp.loading[path] {
+		return nil, fmt.Errorf("import cycle through %s", path)
+	}
+	imp.loading[path] = true
+	defer delete(imp.loading, path)
+
+	files, enums, diags := imp.Load(imp.fset, pkgDir)
+	if err := diags.Err(); err != nil {
+		return nil, err
+	}
+	pkg, _, diags := Files(imp.fset, path, files, imp)
+	if err := diags.Err(); err != nil {
+		return nil, err
+	}
+	imp.packages[path] = pkg
+	imp.enums[path] = enums
+	return pkg, nil
+}
+
+// Enums returns the enums declared by the holang package at path, once
+// imported.
+func (imp *Importer) Enums(path string) []*parser.Enum {
+	return imp.enums[path]
+}
+
+// moduleDir returns the directory of the package at path if it belongs to
+// the main module of dir, the current directory if empty.
+func moduleDir(path, dir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	for {
+		if module, ok := modulePath(filepath.Join(dir, "go.mod")); ok {
+			if path == module {
+				return dir, true
+			}
+			if strings.HasPrefix(path, module+"/") {
+				return filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(path, module+"/"))), true
+			}
+			return "", false
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// modulePath returns the path of the module declared by the go.mod file at
+// path, if it exists.
+func modulePath(path string) (string, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`), true
+		}
+	}
+	return "", false
+}
+
+// holdsHolang reports whether dir holds holang files.
+func holdsHolang(dir string) bool {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasSuffix(info.Name(), ".ho") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,300 @@
+// Package conformance measures how much of Go the holang parser accepts: it
+// parses Go files with both the holang parser and go/parser, and compares
+// the syntax trees, positions included if asked for.
+//
+// Holang being a superset of Go, the trees should be the same for every
+// valid Go file but for what holang deliberately reads differently: match
+// and enum are keywords, and ** is an operator.
+package conformance
+
+import (
+	"errors"
+	"fmt"
+	goast "go/ast"
+	goparser "go/parser"
+	gotoken "go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"holang/pkg/driver"
+)
+
+// Mode controls the comparison of the trees.
+type Mode uint
+
+const (
+	// Positions compares the positions of the nodes, by offset.
+	Positions Mode = 1 << iota
+)
+
+// ErrInvalid is returned by File for the files go/parser rejects, which
+// conformance says nothing about.
+var ErrInvalid = errors.New("not a valid Go file")
+
+// Mismatch is a difference between the trees of a file.
+type Mismatch struct {
+	Pos  gotoken.Position // position of the innermost node of go/parser holding the difference
+	Path string           // path of the difference from the root of the trees, e.g. Decls[1].Type
+	Got  string           // value in the holang tree
+	Want string           // value in the go/parser tree
+}
+
+func (m *Mismatch) Error() string {
+	return fmt.Sprintf("%s: %s: got %s, want %s", m.Pos, m.Path, m.Got, m.Want)
+}
+
+// File parses the Go source src of the file at path with both parsers and
+// returns nil if they agree, as far as mode goes. Otherwise, it returns
+// ErrInvalid, a diagnostics.List of the errors of the holang parser or the
+// first Mismatch of the trees.
+func File(path string, src []byte, mode Mode) error {
+	gofset := gotoken.NewFileSet()
+	want, err := goparser.ParseFile(gofset, path, src, goparser.ParseComments|goparser.SkipObjectResolution)
+	if err != nil {
+		return ErrInvalid
+	}
+	fset := gotoken.NewFileSet()
+	got, _, diags := driver.Parse(fset, path, src)
+	if err := diags.Err(); err != nil {
+		return err
+	}
+	c := &comparison{mode: mode, fset: fset, gofset: gofset}
+	return c.compare(&fieldPath{field: "File"}, reflect.ValueOf(got), reflect.ValueOf(*want))
+}
+
+// comparison walks the trees of a file.
+type comparison struct {
+	mode   Mode             // what to compare
+	fset   *gotoken.FileSet // file set of the holang tree
+	gofset *gotoken.FileSet // file set of the go/parser tree
+	pos    gotoken.Pos      // position of the innermost node of go/parser walked
+}
+
+var (
+	posType    = reflect.TypeOf(gotoken.NoPos)
+	nodeType   = reflect.TypeOf((*goast.Node)(nil)).Elem()
+	objectType = reflect.TypeOf((*goast.Object)(nil))
+	scopeType  = reflect.TypeOf((*goast.Scope)(nil))
+)
+
+// ignored are the fields go/parser fills in with more than the source
+// parsed: the bounds of the file, the ends of literals, the Go version of
+// the build constraints and the identifiers left unresolved.
+var ignored = map[string]bool{
+	"FileStart":  true,
+	"FileEnd":    true,
+	"ValueEnd":   true,
+	"GoVersion":  true,
+	"Unresolved": true,
+}
+
+// fieldPath is the path of a value from the root of the trees, only
+// spelled out for mismatches.
+type fieldPath struct {
+	parent *fieldPath
+	field  string // name of the field holding the value, empty for an element
+	index  int    // index of the element
+}
+
+func (p *fieldPath) String() string {
+	s := ""
+	if p.parent != nil {
+		s = p.parent.String()
+	}
+	if p.field == "" {
+		return fmt.Sprintf("%s[%d]", s, p.index)
+	}
+	if s == "" {
+		return p.field
+	}
+	return s + "." + p.field
+}
+
+// compare compares the value got of the holang tree with the value want of
+// the go/parser tree, at p.
+func (c *comparison) compare(p *fieldPath, got, want reflect.Value) error {
+	if got.Type() != want.Type() {
+		return c.mismatch(p, got.Type().String(), want.Type().String())
+	}
+	if want.CanInterface() && want.Type().Implements(nodeType) && !isNil(want) {
+		if pos := want.Interface().(goast.Node).Pos(); pos.IsValid() {
+			c.pos = pos
+		}
+	}
+	switch want.Type() {
+	case posType:
+		if c.mode&Positions == 0 {
+			return nil
+		}
+		return c.comparePos(p, gotoken.Pos(got.Int()), gotoken.Pos(want.Int()))
+	case objectType, scopeType:
+		// Identifiers are left unresolved
+		return nil
+	}
+
+	switch want.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if got.IsNil() || want.IsNil() {
+			if got.IsNil() != want.IsNil() {
+				return c.mismatch(p, describe(got), describe(want))
+			}
+			return nil
+		}
+		if want.Kind() == reflect.Interface && got.Elem().Type() != want.Elem().Type() {
+			return c.mismatch(p, got.Elem().Type().String(), want.Elem().Type().String())
+		}
+		return c.compare(p, got.Elem(), want.Elem())
+	case reflect.Struct:
+		for i := 0; i < want.NumField(); i++ {
+			field := want.Type().Field(i)
+			if ignored[field.Name] {
+				continue
+			}
+			if err := c.compare(&fieldPath{parent: p, field: field.Name}, got.Field(i), want.Field(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Slice:
+		// Empty slices and nil slices are the same
+		for i := 0; i < got.Len() && i < want.Len(); i++ {
+			if err := c.compare(&fieldPath{parent: p, index: i}, got.Index(i), want.Index(i)); err != nil {
+				return err
+			}
+		}
+		if got.Len() != want.Len() {
+			return c.mismatch(p, fmt.Sprintf("%d elements", got.Len()), fmt.Sprintf("%d elements", want.Len()))
+		}
+		return nil
+	case reflect.Map:
+		// Only the objects of scopes are maps
+		return nil
+	}
+	if got.Interface() != want.Interface() {
+		return c.mismatch(p, fmt.Sprintf("%#v", got.Interface()), fmt.Sprintf("%#v", want.Interface()))
+	}
+	return nil
+}
+
+// comparePos compares positions by their offsets in their file.
+func (c *comparison) comparePos(p *fieldPath, got, want gotoken.Pos) error {
+	if got.IsValid() != want.IsValid() {
+		return c.mismatch(p, c.fset.Position(got).String(), c.gofset.Position(want).String())
+	}
+	if !want.IsValid() {
+		return nil
+	}
+	if g, w := c.fset.Position(got), c.gofset.Position(want); g.Offset != w.Offset {
+		return c.mismatch(p, fmt.Sprintf("%d:%d", g.Line, g.Column), fmt.Sprintf("%d:%d", w.Line, w.Column))
+	}
+	return nil
+}
+
+func (c *comparison) mismatch(p *fieldPath, got, want string) error {
+	return &Mismatch{Pos: c.gofset.Position(c.pos), Path: p.String(), Got: got, Want: want}
+}
+
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		return v.IsNil()
+	}
+	return false
+}
+
+func describe(v reflect.Value) string {
+	if v.IsNil() {
+		return "nil"
+	}
+	return v.Elem().Type().String()
+}
+
+// Package is the conformance of the Go files of a directory.
+type Package struct {
+	Dir      string    // directory, relative to the root walked
+	Files    int       // number of valid Go files
+	Failures []Failure // files on which the parsers disagree
+}
+
+// Failure is a file on which the parsers disagree.
+type Failure struct {
+	Path string
+	Err  error // as returned by File
+}
+
+// Passed returns the number of files on which the parsers agree.
+func (p *Package) Passed() int {
+	return p.Files - len(p.Failures)
+}
+
+// Rate returns the fraction of the files on which the parsers agree.
+func (p *Package) Rate() float64 {
+	if p.Files == 0 {
+		return 1
+	}
+	return float64(p.Passed()) / float64(p.Files)
+}
+
+// Dir checks every valid Go file of the tree rooted at root, test data
+// aside, comparing the trees as far as mode goes, and returns the
+// conformance of every directory holding some, sorted by directory.
+func Dir(root string, mode Mode) ([]*Package, error) {
+	pkgs := map[string]*Package{}
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			// Test data holds invalid files on purpose
+			if info.Name() == "testdata" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		err = File(path, src, mode)
+		if err == ErrInvalid {
+			return nil
+		}
+		dir, _ := filepath.Rel(root, filepath.Dir(path))
+		pkg := pkgs[dir]
+		if pkg == nil {
+			pkg = &Package{Dir: filepath.ToSlash(dir)}
+			pkgs[dir] = pkg
+		}
+		pkg.Files++
+		if err != nil {
+			pkg.Failures = append(pkg.Failures, Failure{Path: path, Err: err})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*Package, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		list = append(list, pkg)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Dir < list[j].Dir })
+	return list, nil
+}
+
+// Total returns the conformance of pkgs as a whole.
+func Total(pkgs []*Package) *Package {
+	total := &Package{}
+	for _, pkg := range pkgs {
+		total.Files += pkg.Files
+		total.Failures = append(total.Failures, pkg.Failures...)
+	}
+	return total
+}
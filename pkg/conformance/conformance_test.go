@@ -0,0 +1,70 @@
+package conformance_test
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"holang/pkg/conformance"
+)
+
+// minRate is the pass rate over the standard library below which
+// TestStandardLibrary fails, positions compared. Raise it as the parser
+// accepts more of Go.
+const minRate = 0.92
+
+func TestFile(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		ok   bool
+	}{
+		{"declarations", "package p\n\nimport \"fmt\"\n\nconst c = 1 << 3\n\ntype T struct{ A, B int }\n\nfunc (t *T) F() { fmt.Println(t.A) }\n", true},
+		{"statements", "package p\n\nfunc f(xs []int) (n int) {\n\tfor _, x := range xs {\n\t\tswitch {\n\t\tcase x > 0:\n\t\t\tn += x\n\t\t}\n\t}\n\treturn\n}\n", true},
+		{"comments", "// Package p.\npackage p\n\n// F does nothing.\nfunc F() {} // really\n", true},
+		{"pointer to pointer", "package p\n\nvar a, b int\nvar c = a**&b\n", false},
+		{"match identifier", "package p\n\nvar match = 1\n", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := conformance.File("p.go", []byte(test.src), conformance.Positions)
+			if test.ok && err != nil {
+				t.Errorf("got %v, want agreement", err)
+			}
+			if !test.ok && err == nil {
+				t.Error("got agreement, want a difference")
+			}
+		})
+	}
+}
+
+func TestFileInvalid(t *testing.T) {
+	if err := conformance.File("p.go", []byte("package p\n\nfunc f() {\n"), 0); err != conformance.ErrInvalid {
+		t.Errorf("got %v, want %v", err, conformance.ErrInvalid)
+	}
+}
+
+// TestStandardLibrary checks that the holang parser agrees with go/parser on
+// enough of the Go files of the standard library.
+func TestStandardLibrary(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the standard library in short mode")
+	}
+	root := filepath.Join(build.Default.GOROOT, "src")
+	if _, err := os.Stat(root); err != nil {
+		t.Skipf("no standard library: %v", err)
+	}
+	pkgs, err := conformance.Dir(root, conformance.Positions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := conformance.Total(pkgs)
+	if total.Files == 0 {
+		t.Fatalf("no Go files in %s", root)
+	}
+	t.Logf("%d/%d files pass (%.1f%%)", total.Passed(), total.Files, 100*total.Rate())
+	if total.Rate() < minRate {
+		t.Errorf("pass rate %.1f%%, want at least %.1f%% (run ho conformance -pos -v for the failures)", 100*total.Rate(), 100*minRate)
+	}
+}
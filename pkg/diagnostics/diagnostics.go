@@ -0,0 +1,102 @@
+package diagnostics
+
+import (
+	"fmt"
+	"sort"
+	"text/scanner"
+)
+
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+	Note
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	case Note:
+		return "note"
+	}
+	return fmt.Sprintf("severity(%d)", int(s))
+}
+
+// Span is a range of source code, from Start included to End excluded.
+type Span struct {
+	Start scanner.Position
+	End   scanner.Position
+	Label string // optional, printed next to the underline
+}
+
+// Fix is a suggested edit: replacing Span with Replacement. An empty span
+// (Start == End) denotes an insertion.
+type Fix struct {
+	Message     string
+	Span        Span
+	Replacement string
+}
+
+type Diagnostic struct {
+	Severity  Severity
+	Code      string // stable identifier of the kind of diagnostic, e.g. L0001
+	Message   string
+	Primary   Span   // where the problem is
+	Secondary []Span // related locations
+	Notes     []string
+	Fixes     []Fix
+}
+
+// Error formats the diagnostic on a single line, the way Go tools do.
+func (d *Diagnostic) Error() string {
+	if d.Primary.Start.IsValid() {
+		return fmt.Sprintf("%s: %s", d.Primary.Start, d.header())
+	}
+	return d.header()
+}
+
+func (d *Diagnostic) header() string {
+	if d.Code == "" {
+		return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s[%s]: %s", d.Severity, d.Code, d.Message)
+}
+
+// List is a list of diagnostics, usable as an error.
+type List []*Diagnostic
+
+func (l List) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// Err returns an error equivalent to this list, or nil if it does not hold
+// any diagnostic of Error severity.
+func (l List) Err() error {
+	for _, d := range l {
+		if d.Severity == Error {
+			return l
+		}
+	}
+	return nil
+}
+
+// Sort orders the list by file and primary position.
+func (l List) Sort() {
+	sort.SliceStable(l, func(i, j int) bool {
+		a, b := l[i].Primary.Start, l[j].Primary.Start
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Offset < b.Offset
+	})
+}
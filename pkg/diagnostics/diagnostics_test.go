@@ -0,0 +1,112 @@
+package diagnostics_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"text/scanner"
+
+	"holang/pkg/diagnostics"
+)
+
+// position returns the position of the byte at offset in src.
+func position(filename, src string, offset int) scanner.Position {
+	line := strings.Count(src[:offset], "\n") + 1
+	column := offset - strings.LastIndexByte(src[:offset], '\n')
+	return scanner.Position{Filename: filename, Offset: offset, Line: line, Column: column}
+}
+
+// spanOf returns the span of the first occurrence of text in src.
+func spanOf(src, text string) diagnostics.Span {
+	start := strings.Index(src, text)
+	return diagnostics.Span{
+		Start: position("p.ho", src, start),
+		End:   position("p.ho", src, start+len(text)),
+	}
+}
+
+// TestRender checks that the excerpts printed underline the spans, their
+// first line alone for those spanning several lines.
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		span func(src string) diagnostics.Span
+		want string
+	}{
+		{
+			"single line",
+			"package p\n\nvar x = y ** \"s\"\n",
+			func(src string) diagnostics.Span { return spanOf(src, `y ** "s"`) },
+			"error[T0001]: invalid operand\n" +
+				" --> p.ho:3:9\n" +
+				"  |\n" +
+				"3 | var x = y ** \"s\"\n" +
+				"  |         ^^^^^^^^\n",
+		},
+		{
+			"multiple lines",
+			"package p\n\nvar x = f(1,\n\t2)\n",
+			func(src string) diagnostics.Span { return spanOf(src, "f(1,\n\t2)") },
+			"error[T0001]: invalid operand\n" +
+				" --> p.ho:3:9\n" +
+				"  |\n" +
+				"3 | var x = f(1,\n" +
+				"  |         ^^^^\n",
+		},
+		{
+			"tab indentation",
+			"package p\n\nfunc f() {\n\t\tx := y ** 2\n}\n",
+			func(src string) diagnostics.Span { return spanOf(src, "y ** 2") },
+			"error[T0001]: invalid operand\n" +
+				" --> p.ho:4:8\n" +
+				"  |\n" +
+				"4 | \t\tx := y ** 2\n" +
+				"  | \t\t     ^^^^^^\n",
+		},
+		{
+			"end of file",
+			"package p\n\nvar x = (",
+			func(src string) diagnostics.Span {
+				pos := position("p.ho", src, len(src))
+				return diagnostics.Span{Start: pos, End: pos}
+			},
+			"error[T0001]: invalid operand\n" +
+				" --> p.ho:3:10\n" +
+				"  |\n" +
+				"3 | var x = (\n" +
+				"  |          ^\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := &diagnostics.Diagnostic{Code: "T0001", Message: "invalid operand", Primary: test.span(test.src)}
+			r := diagnostics.Renderer{Sources: map[string][]byte{"p.ho": []byte(test.src)}}
+			var b bytes.Buffer
+			if err := r.Render(&b, d); err != nil {
+				t.Fatal(err)
+			}
+			if got := b.String(); got != test.want {
+				t.Errorf("got\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+// TestSort checks that diagnostics are ordered by file, then by offset, and
+// that those at the same position keep their order.
+func TestSort(t *testing.T) {
+	at := func(filename string, offset int) *diagnostics.Diagnostic {
+		pos := scanner.Position{Filename: filename, Offset: offset, Line: 1, Column: offset + 1}
+		return &diagnostics.Diagnostic{Primary: diagnostics.Span{Start: pos, End: pos}}
+	}
+	b10, a20, b5, a3, a20again := at("b.ho", 10), at("a.ho", 20), at("b.ho", 5), at("a.ho", 3), at("a.ho", 20)
+	l := diagnostics.List{b10, a20, b5, a3, a20again}
+	l.Sort()
+	want := diagnostics.List{a3, a20, a20again, b5, b10}
+	for i := range want {
+		if l[i] != want[i] {
+			t.Errorf("got %s at %d, want %s", l[i].Primary.Start, i, want[i].Primary.Start)
+		}
+	}
+}
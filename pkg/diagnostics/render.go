@@ -0,0 +1,129 @@
+package diagnostics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Renderer prints diagnostics with an excerpt of the source they point at,
+// underlining the offending code:
+//
+//	error[L0009]: unterminated string
+//	 --> main.ho:5:9
+//	  |
+//	5 | var d = "abc
+//	  |         ^^^^
+//	  = help: add the closing quote: `"`
+type Renderer struct {
+	Sources map[string][]byte // contents of the files, by filename
+}
+
+func (r *Renderer) Render(w io.Writer, d *Diagnostic) error {
+	var b bytes.Buffer
+
+	spans := append([]Span{d.Primary}, d.Secondary...)
+	gutter := 0
+	for _, span := range spans {
+		if n := len(strconv.Itoa(span.Start.Line)); n > gutter {
+			gutter = n
+		}
+	}
+	pad := strings.Repeat(" ", gutter)
+
+	fmt.Fprintln(&b, d.header())
+	for i, span := range spans {
+		if !span.Start.IsValid() {
+			continue
+		}
+		if i == 0 {
+			fmt.Fprintf(&b, "%s--> %s\n", pad, span.Start)
+		} else {
+			fmt.Fprintf(&b, "%s::: %s\n", pad, span.Start)
+		}
+		line, ok := r.line(span)
+		if !ok {
+			continue
+		}
+		marker := '^'
+		if i > 0 {
+			marker = '-'
+		}
+		fmt.Fprintf(&b, "%s |\n", pad)
+		fmt.Fprintf(&b, "%*d | %s\n", gutter, span.Start.Line, line)
+		fmt.Fprintf(&b, "%s | %s\n", pad, underline(line, span, marker))
+	}
+	for _, note := range d.Notes {
+		fmt.Fprintf(&b, "%s = note: %s\n", pad, note)
+	}
+	for _, fix := range d.Fixes {
+		fmt.Fprintf(&b, "%s = help: %s: `%s`\n", pad, fix.Message, fix.Replacement)
+	}
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// RenderAll renders every diagnostic of the list, separated by blank lines.
+func (r *Renderer) RenderAll(w io.Writer, l List) error {
+	for i, d := range l {
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		if err := r.Render(w, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// line returns the source line the span starts on, without its newline.
+func (r *Renderer) line(span Span) (string, bool) {
+	src, ok := r.Sources[span.Start.Filename]
+	if !ok || span.Start.Offset > len(src) {
+		return "", false
+	}
+	start := bytes.LastIndexByte(src[:span.Start.Offset], '\n') + 1
+	end := bytes.IndexByte(src[start:], '\n')
+	if end < 0 {
+		end = len(src) - start
+	}
+	return strings.TrimRight(string(src[start:start+end]), "\r"), true
+}
+
+// underline returns the marker line for a span starting on line. Tabs of the
+// source line are kept so that the markers stay aligned with the code. Spans
+// continuing on the next lines are underlined up to the end of the first one.
+func underline(line string, span Span, marker rune) string {
+	var b strings.Builder
+	column := 1
+	for _, r := range line {
+		if column >= span.Start.Column {
+			break
+		}
+		if r == '\t' {
+			b.WriteRune('\t')
+		} else {
+			b.WriteRune(' ')
+		}
+		column++
+	}
+	width := 1
+	if span.End.Line == span.Start.Line && span.End.Column > span.Start.Column {
+		width = span.End.Column - span.Start.Column
+	} else if span.End.Line > span.Start.Line {
+		if rest := utf8.RuneCountInString(line) - span.Start.Column + 1; rest > 1 {
+			width = rest
+		}
+	}
+	b.WriteString(strings.Repeat(string(marker), width))
+	if span.Label != "" {
+		b.WriteString(" " + span.Label)
+	}
+	return b.String()
+}
@@ -0,0 +1,380 @@
+// Package driver builds packages mixing holang and Go files with the go
+// command.
+//
+// Every holang file is translated to a Go file named after it, with a .ho.go
+// extension, the holang files of a package being lowered together. The
+// helper functions their translations call are declared once per package,
+// in a holang_helpers.go file. Within a module, the translations of all the
+// holang files of the module are laid over its tree with the -overlay flag
+// of the go command, so that packages import each other whatever their
+// language.
+// Outside of any module, the package is copied along with its translations
+// into a generated module.
+//
+// The translations carry //line directives, so that the positions reported
+// by the go command, stack traces and debuggers point at the holang files.
+// Build.MapPositions maps the remaining positions in the translations back
+// to the holang files as well.
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	gotoken "go/token"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"holang/pkg/check"
+	"holang/pkg/diagnostics"
+	"holang/pkg/printer"
+)
+
+// Ext is the extension of holang files.
+const Ext = ".ho"
+
+// Package is a directory of holang and Go files.
+type Package struct {
+	Dir     string   // absolute path of the directory
+	HoFiles []string // names of the holang files
+	GoFiles []string // names of the Go files, tests aside
+}
+
+// LoadDir lists the files of the package in dir.
+func LoadDir(dir string) (*Package, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	pkg := &Package{Dir: dir}
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(name, Ext):
+			pkg.HoFiles = append(pkg.HoFiles, name)
+		case strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go"):
+			pkg.GoFiles = append(pkg.GoFiles, name)
+		}
+	}
+	return pkg, nil
+}
+
+// helpersName is the name of the Go file declaring the helper functions
+// called by the translations of the holang files of a package.
+const helpersName = "holang_helpers.go"
+
+// goName returns the name of the translation of the holang file name.
+func goName(name string) string {
+	return name + ".go"
+}
+
+// Build is the build of a package in progress: its holang files are
+// translated and ready to be handed to the go command.
+type Build struct {
+	Dir     string            // directory to run the go command in
+	Target  string            // package or file to build, relative to Dir
+	Sources map[string][]byte // contents of the holang files translated, by path
+	imports *check.Importer   // importer of the packages of the holang files
+	work    string            // temporary directory holding the translations
+	overlay string            // path of the overlay file, empty outside of modules
+	files   map[string]origin // sources of the Go files seen by the go command, by absolute path
+	file    bool              // whether a holang file is built alone, its translation declaring its helpers
+}
+
+// origin is the source of a Go file seen by the go command.
+type origin struct {
+	path  string          // absolute path of the source
+	src   []byte          // holang source, nil for Go files
+	gen   []byte          // Go translation of src
+	lines printer.LineMap // map of the lines of gen, nil for Go files
+}
+
+// New prepares the build of the package in the directory at path, or of the
+// holang file at path alone. If some holang files have errors, it returns
+// them as a diagnostics.List, along with the closed build holding their
+// sources.
+func New(path string) (*Build, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	var pkg *Package
+	target := "."
+	if info.IsDir() {
+		if pkg, err = LoadDir(path); err != nil {
+			return nil, err
+		}
+	} else {
+		if !strings.HasSuffix(path, Ext) {
+			return nil, fmt.Errorf("%s is not a holang file", path)
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		pkg = &Package{Dir: filepath.Dir(abs), HoFiles: []string{filepath.Base(abs)}}
+		target = "." + string(filepath.Separator) + goName(pkg.HoFiles[0])
+	}
+
+	work, err := ioutil.TempDir("", "ho-build")
+	if err != nil {
+		return nil, err
+	}
+	b := &Build{
+		Dir:     pkg.Dir,
+		Target:  target,
+		Sources: map[string][]byte{},
+		imports: NewImporter(),
+		work:    work,
+		files:   map[string]origin{},
+		file:    !info.IsDir(),
+	}
+	if root, ok := moduleRoot(pkg.Dir); ok {
+		err = b.overlayModule(root, pkg)
+	} else {
+		err = b.generateModule(pkg)
+	}
+	if err != nil {
+		b.Close()
+		if _, ok := err.(diagnostics.List); ok {
+			return b, err
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+// Close removes the translations.
+func (b *Build) Close() error {
+	return os.RemoveAll(b.work)
+}
+
+// Command returns the go command running verb, e.g. "build", over the
+// package, with the flags given.
+func (b *Build) Command(verb string, flags ...string) *exec.Cmd {
+	args := []string{verb}
+	if b.overlay != "" {
+		args = append(args, "-overlay", b.overlay)
+	}
+	args = append(args, flags...)
+	cmd := exec.Command("go", append(args, b.Target)...)
+	cmd.Dir = b.Dir
+	return cmd
+}
+
+// moduleRoot returns the root of the module holding dir, if any.
+func moduleRoot(dir string) (string, bool) {
+	for {
+		if info, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil && !info.IsDir() {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// overlayModule translates the holang files of the module at root, those of
+// pkg if it only holds some of them, and lays them over the module tree.
+func (b *Build) overlayModule(root string, pkg *Package) error {
+	pkgs := []*Package{}
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return err
+		}
+		name := info.Name()
+		if path != root {
+			// Like the go command, skip the directories that hold no
+			// package of the module
+			if name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+		}
+		if path == pkg.Dir {
+			pkgs = append(pkgs, pkg)
+			return nil
+		}
+		p, err := LoadDir(path)
+		if err != nil {
+			return err
+		}
+		if len(p.HoFiles) > 0 {
+			pkgs = append(pkgs, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	replace := map[string]string{}
+	diags := diagnostics.List{}
+	for i, p := range pkgs {
+		dir := filepath.Join(b.work, fmt.Sprint(i))
+		if err := os.Mkdir(dir, 0777); err != nil {
+			return err
+		}
+		written, errs, err := b.translate(p, dir, p.Dir)
+		if err != nil {
+			return err
+		}
+		diags = append(diags, errs...)
+		for seen, gen := range written {
+			replace[seen] = gen
+		}
+	}
+	if diags.Err() != nil {
+		diags.Sort()
+		return diags
+	}
+
+	overlay, err := json.Marshal(struct{ Replace map[string]string }{replace})
+	if err != nil {
+		return err
+	}
+	b.overlay = filepath.Join(b.work, "overlay.json")
+	return ioutil.WriteFile(b.overlay, overlay, 0666)
+}
+
+// generateModule copies pkg along with its translations into a module of
+// its own.
+func (b *Build) generateModule(pkg *Package) error {
+	_, diags, err := b.translate(pkg, b.work, b.work)
+	if err != nil {
+		return err
+	}
+	if diags.Err() != nil {
+		return diags
+	}
+	for _, name := range pkg.GoFiles {
+		src, err := ioutil.ReadFile(filepath.Join(pkg.Dir, name))
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(b.work, name)
+		if err := ioutil.WriteFile(path, src, 0666); err != nil {
+			return err
+		}
+		b.files[path] = origin{path: filepath.Join(pkg.Dir, name)}
+	}
+
+	version, err := exec.Command("go", "env", "GOVERSION").Output()
+	if err != nil {
+		return fmt.Errorf("go env GOVERSION: %v", err)
+	}
+	mod := fmt.Sprintf("module %s\n\ngo %s\n", modulePath(pkg.Dir), languageVersion(string(version)))
+	if err := ioutil.WriteFile(filepath.Join(b.work, "go.mod"), []byte(mod), 0666); err != nil {
+		return err
+	}
+	b.Dir = b.work
+	return nil
+}
+
+// translate translates the holang files of pkg, lowered together, to Go
+// files written in the directory gen, which the go command sees in the
+// directory seen. It returns the paths of the files written by the paths the
+// go command sees them at. The holang errors are returned as diagnostics,
+// other errors as err.
+func (b *Build) translate(pkg *Package, gen, seen string) (map[string]string, diagnostics.List, error) {
+	for _, name := range pkg.GoFiles {
+		if name == helpersName {
+			return nil, nil, fmt.Errorf("%s: the file name is reserved for the helpers of the holang files", filepath.Join(pkg.Dir, name))
+		}
+	}
+	fset := gotoken.NewFileSet()
+	// The go command reports the errors of the Go files
+	goFiles, _ := parseGoFiles(fset, pkg)
+	paths, srcs := []string{}, [][]byte{}
+	for _, name := range pkg.HoFiles {
+		path := filepath.Join(pkg.Dir, name)
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		b.Sources[displayPath(path)] = src
+		paths, srcs = append(paths, displayPath(path)), append(srcs, src)
+	}
+	files, helpers, diags := LowerFiles(fset, paths, srcs, goFiles, b.imports)
+	if diags.Err() != nil {
+		return nil, diags, nil
+	}
+	if helpers != nil && b.file {
+		mergeHelpers(files[0], helpers)
+		helpers = nil
+	}
+
+	written := map[string]string{}
+	for i, file := range files {
+		path := filepath.Join(pkg.Dir, pkg.HoFiles[i])
+		name := goName(pkg.HoFiles[i])
+		// The directives are relative to the Go file, wherever the go
+		// command sees it: they refer to absolute paths
+		config := printer.Config{LineDirectives: true, SourceName: path, Filename: filepath.Join(seen, name)}
+		out, lines, err := config.Source(fset, *file)
+		if err != nil {
+			return nil, nil, err
+		}
+		o := origin{path: path, src: srcs[i], gen: out, lines: lines}
+		// The go command reports positions in overlaid files by either path
+		b.files[filepath.Join(seen, name)], b.files[filepath.Join(gen, name)] = o, o
+		if err := ioutil.WriteFile(filepath.Join(gen, name), out, 0666); err != nil {
+			return nil, nil, err
+		}
+		written[filepath.Join(seen, name)] = filepath.Join(gen, name)
+	}
+	if helpers != nil {
+		out, _, err := printer.Source(fset, *helpers)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := ioutil.WriteFile(filepath.Join(gen, helpersName), out, 0666); err != nil {
+			return nil, nil, err
+		}
+		written[filepath.Join(seen, helpersName)] = filepath.Join(gen, helpersName)
+	}
+	return written, diags, nil
+}
+
+// modulePath returns the path of the module generated for the package in
+// dir: the name of the directory if it is a valid one.
+func modulePath(dir string) string {
+	name := filepath.Base(dir)
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-._~", r)) {
+			return "main"
+		}
+	}
+	if name == "" || name == "." || strings.HasPrefix(name, ".") {
+		return "main"
+	}
+	return name
+}
+
+// languageVersion returns the language version of the go toolchain version,
+// e.g. 1.21 for go1.21.3.
+func languageVersion(version string) string {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "go")
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return "1.12"
+	}
+	minor := parts[1]
+	if i := strings.IndexFunc(minor, func(r rune) bool { return r < '0' || r > '9' }); i >= 0 {
+		minor = minor[:i]
+	}
+	return parts[0] + "." + minor
+}
@@ -0,0 +1,188 @@
+package driver_test
+
+import (
+	goast "go/ast"
+	gotoken "go/token"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"holang/pkg/check"
+	"holang/pkg/driver"
+	"holang/pkg/internal/testdir"
+	"holang/pkg/lower"
+)
+
+// powers is a package whose holang files both use integer powers, of
+// variables declared in the other files.
+var powers = map[string]string{
+	"a.ho": "package main\n\nvar base = 3\n\nfunc square(n int) int { return n ** 2 }\n\nfunc main() {\n\tprintln(square(exponent), cube())\n}\n",
+	"b.ho": "package main\n\nfunc cube() int { return base ** 3 }\n",
+	"c.go": "package main\n\nvar exponent = 4\n",
+}
+
+func TestLoadFiles(t *testing.T) {
+	dir := testdir.Write(t, powers)
+	fset := gotoken.NewFileSet()
+	imports := driver.NewImporter()
+	files, diags := driver.LoadFiles(fset, dir, imports)
+	if err := diags.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, diags := check.Files(fset, "main", files, imports); diags.Err() != nil {
+		t.Fatal(diags.Err())
+	}
+	helpers := 0
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			if f, ok := decl.(*goast.FuncDecl); ok && f.Name.Name == "holangPowUint" {
+				helpers++
+			}
+		}
+	}
+	if helpers != 1 {
+		t.Errorf("got %d declarations of the helper, want 1", helpers)
+	}
+}
+
+func TestMatchExhaustiveness(t *testing.T) {
+	// The enum in another file of the package
+	local := func(match string) map[string]string {
+		return map[string]string{
+			"a.ho": "package main\n\nenum Color { Red, Green, Blue }\n\nfunc main() {}\n",
+			"b.ho": "package main\n\nfunc f(c Color) int {\n\treturn " + match + "\n}\n",
+		}
+	}
+	// The enum in a holang package imported
+	imported := func(match string) map[string]string {
+		return map[string]string{
+			"go.mod":           "module example.com/m\n\ngo 1.16\n",
+			"colors/colors.ho": "package colors\n\nenum Color { Red, Green, Blue }\n",
+			"b.ho":             "package main\n\nimport . \"example.com/m/colors\"\n\nfunc main() {}\n\nfunc f(c Color) int {\n\treturn " + match + "\n}\n",
+		}
+	}
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+	}{
+		{"missing variants", local("match c { Red => 1 }"), []string{lower.NonExhaustiveMatch}},
+		{"all variants", local("match c { Red, Green => 1; Blue => 2 }"), nil},
+		{"default arm", local("match c { Red => 1; _ => 2 }"), nil},
+		{"not an enum", local("match int(c) { 0 => 1 }"), nil},
+		{"imported missing variants", imported("match c { Red, Green => 1 }"), []string{lower.NonExhaustiveMatch}},
+		{"imported all variants", imported("match c { Red, Green => 1; Blue => 2 }"), nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := testdir.Write(t, test.files)
+			_, diags := driver.LoadFiles(gotoken.NewFileSet(), dir, driver.NewImporter())
+			got := []string{}
+			for _, d := range diags {
+				got = append(got, d.Code)
+				if filepath.Base(d.Primary.Start.Filename) != "b.ho" {
+					t.Errorf("%s reported in %s, want b.ho", d.Code, d.Primary.Start.Filename)
+				}
+			}
+			if strings.Join(got, " ") != strings.Join(test.want, " ") {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+// module is a module whose main package imports a package of holang files
+// using integer powers, laid over the module tree.
+var module = map[string]string{
+	"go.mod":      "module example.com/m\n\ngo 1.16\n",
+	"main.ho":     "package main\n\nimport \"example.com/m/lib\"\n\nfunc main() {\n\tprintln(lib.Square(2), lib.Cube(2), lib.Base ** 2)\n}\n",
+	"lib/a.ho":    "package lib\n\nfunc Square(n int) int { return n ** 2 }\n",
+	"lib/b.ho":    "package lib\n\nfunc Cube(n int) int { return n ** 3 }\n",
+	"lib/base.go": "package lib\n\nvar Base = 5\n",
+}
+
+func TestBuild(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the go command in short mode")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("no go command")
+	}
+	tests := []struct {
+		name  string
+		files map[string]string
+		path  string // path built, relative to the directory of the files
+		want  string
+	}{
+		{"package", powers, ".", "16 27\n"},
+		{"module", module, ".", "4 8 25\n"},
+		{"file", map[string]string{"main.ho": "package main\n\nfunc main() {\n\tn := 2\n\tprintln(n ** 10)\n}\n"}, "main.ho", "1024\n"},
+		{"unsigned", map[string]string{"main.ho": "package main\n\nvar u uint64 = 1 << 63\n\nfunc main() {\n\tn, i := uint8(3), int8(-1)\n\tprintln(2 ** u, i ** u, (-2) ** n)\n}\n"}, "main.ho", "0 1 -8\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := testdir.Write(t, test.files)
+			if got := run(t, filepath.Join(dir, test.path)); got != test.want {
+				t.Errorf("got output %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+// TestMapPositions checks that the errors of a build point at the files of
+// the package, holang and Go ones, inside and outside of a module.
+func TestMapPositions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds packages")
+	}
+	files := map[string]string{
+		"a.ho": "package main\n\nfunc main() { println(f() ** 2) }\n",
+		"b.ho": "package main\n\nfunc f() int {\n\tn := len(\"ab\") ** 2\n\treturn \"s\" + n\n}\n",
+		"c.go": "package main\n\nvar x int = \"s\"\n",
+	}
+	for _, inModule := range []bool{false, true} {
+		if inModule {
+			files["go.mod"] = "module example.com/m\n\ngo 1.16\n"
+		}
+		dir := testdir.Write(t, files)
+		b, err := driver.New(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer b.Close()
+		out, err := b.Command("build", "-o", filepath.Join(dir, "main")).CombinedOutput()
+		if err == nil {
+			t.Fatal("build succeeded")
+		}
+		out = b.MapPositions(out)
+		for _, want := range []string{filepath.Join(dir, "b.ho") + ":5:9", filepath.Join(dir, "c.go") + ":3:13"} {
+			if !strings.Contains(string(out), want) {
+				t.Errorf("in module %v: no error at %s in\n%s", inModule, want, out)
+			}
+		}
+		if strings.Contains(string(out), ".ho.go") {
+			t.Errorf("in module %v: errors point at translations:\n%s", inModule, out)
+		}
+	}
+}
+
+// run builds the package or the holang file at path and returns what the
+// executable prints.
+func run(t *testing.T, path string) string {
+	t.Helper()
+	b, err := driver.New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	exe := filepath.Join(t.TempDir(), "main")
+	if out, err := b.Command("build", "-o", exe).CombinedOutput(); err != nil {
+		t.Fatalf("%v\n%s", err, b.MapPositions(out))
+	}
+	out, err := exec.Command(exe).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+	return string(out)
+}
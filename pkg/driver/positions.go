@@ -0,0 +1,115 @@
+package driver
+
+import (
+	"go/scanner"
+	gotoken "go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// position matches the positions in the output of the go command:
+// path:line:column, the column being optional.
+var position = regexp.MustCompile(`(?m)((?:[A-Za-z]:)?[^\s:]+\.(?:go|ho)):(\d+)(?::(\d+))?`)
+
+// MapPositions rewrites the positions in out, the output of a command
+// returned by Command, that point at the translations of holang files or at
+// the copies of Go files, to point at the files of the package instead.
+// Paths are made relative to the current directory, like the go command
+// does, including those of the holang files given by //line directives.
+func (b *Build) MapPositions(out []byte) []byte {
+	return position.ReplaceAllFunc(out, func(match []byte) []byte {
+		m := position.FindSubmatch(match)
+		path := string(m[1])
+		relative := !filepath.IsAbs(path)
+		if relative {
+			path = filepath.Join(b.Dir, path)
+		}
+		if _, ok := b.Sources[displayPath(path)]; ok {
+			return append([]byte(displayPath(path)), match[len(m[1]):]...)
+		}
+		o, ok := b.files[path]
+		if !ok {
+			if relative {
+				// Relative to the directory of the package, where the go
+				// command runs
+				return append([]byte(displayPath(path)), match[len(m[1]):]...)
+			}
+			return match
+		}
+		line, _ := strconv.Atoi(string(m[2]))
+		column, _ := strconv.Atoi(string(m[3]))
+		if o.lines != nil {
+			if line, column, ok = o.position(line, column); !ok {
+				// Generated by lowering
+				return match
+			}
+		}
+
+		s := displayPath(o.path) + ":" + strconv.Itoa(line)
+		if column > 0 {
+			s += ":" + strconv.Itoa(column)
+		}
+		return []byte(s)
+	})
+}
+
+// displayPath returns the absolute path as the go command displays it:
+// relative to the current directory if it is inside of it.
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return "." + string(filepath.Separator) + rel
+	}
+	return path
+}
+
+// position returns the position in the holang source of the position at
+// line and column of its translation. The column is mapped by rank among
+// the tokens of the lines, as both mostly hold the same ones; it is 0 when
+// unknown. ok is false if the line does not come from the holang source.
+func (o origin) position(line, column int) (_, _ int, ok bool) {
+	if line < 1 || line > len(o.lines) || !o.lines[line-1].IsValid() {
+		return 0, 0, false
+	}
+	pos := o.lines[line-1]
+	gen, src := lineTokens(o.gen, line), lineTokens(o.src, pos.Line)
+	rank := -1
+	for i, offset := range gen {
+		if offset > column-1 {
+			break
+		}
+		rank = i
+	}
+	if column < 1 || rank < 0 || rank >= len(src) {
+		return pos.Line, 0, true
+	}
+	return pos.Line, src[rank] + 1, true
+}
+
+// lineTokens returns the offsets of the tokens of line n of src, from the
+// start of the line.
+func lineTokens(src []byte, n int) []int {
+	lines := strings.SplitAfter(string(src), "\n")
+	if n < 1 || n > len(lines) {
+		return nil
+	}
+	text := []byte(lines[n-1])
+	fset := gotoken.NewFileSet()
+	file := fset.AddFile("", -1, len(text))
+	var s scanner.Scanner
+	s.Init(file, text, nil, 0)
+	offsets := []int{}
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == gotoken.EOF || tok == gotoken.SEMICOLON && lit == "\n" {
+			return offsets
+		}
+		offsets = append(offsets, file.Offset(pos))
+	}
+}
@@ -0,0 +1,189 @@
+package driver
+
+import (
+	"bytes"
+	goast "go/ast"
+	goparser "go/parser"
+	goscanner "go/scanner"
+	gotoken "go/token"
+	"go/types"
+	"io/ioutil"
+	"path/filepath"
+	"text/scanner"
+
+	"holang/pkg/check"
+	"holang/pkg/diagnostics"
+	"holang/pkg/lexer"
+	"holang/pkg/lower"
+	"holang/pkg/parser"
+	"holang/pkg/printer"
+)
+
+// Parse parses the holang source src of the file at path, and returns the
+// file along with its holang constructs and every diagnostic found.
+func Parse(fset *gotoken.FileSet, path string, src []byte) (goast.File, parser.Constructs, diagnostics.List) {
+	l := lexer.NewLexer(fset, bytes.NewReader(src), path)
+	p := parser.NewParser(l)
+	file, err := p.ParseFile()
+
+	diags := l.Errors()
+	illegal := map[int]bool{}
+	for _, d := range diags {
+		illegal[d.Primary.Start.Offset] = true
+	}
+	if list, ok := err.(diagnostics.List); ok {
+		for _, d := range list {
+			// The parser fails on illegal tokens, which the lexer reported
+			if !illegal[d.Primary.Start.Offset] {
+				diags = append(diags, d)
+			}
+		}
+	}
+	diags.Sort()
+	return file, p.Constructs(), diags
+}
+
+// Lower parses and lowers the holang source src of the file at path, as the
+// only file of its package, importing packages with importer, and returns
+// the file, declaring the helpers its lowered code calls, along with every
+// diagnostic found.
+func Lower(fset *gotoken.FileSet, path string, src []byte, importer types.Importer) (goast.File, diagnostics.List) {
+	files, helpers, diags := LowerFiles(fset, []string{path}, [][]byte{src}, nil, importer)
+	if helpers != nil {
+		mergeHelpers(files[0], helpers)
+	}
+	return *files[0], diags
+}
+
+// LowerFiles parses the holang sources srcs of the files at paths and, if
+// none of them has syntax errors, lowers them together with goFiles, the
+// Go files of their package, importing packages with importer. It returns
+// the holang files along with the file declaring the helpers their lowered
+// code calls, nil if it calls none, and every diagnostic found.
+func LowerFiles(fset *gotoken.FileSet, paths []string, srcs [][]byte, goFiles []*goast.File, importer types.Importer) ([]*goast.File, *goast.File, diagnostics.List) {
+	files, helpers, _, diags := lowerFiles(fset, paths, srcs, goFiles, importer)
+	return files, helpers, diags
+}
+
+// lowerFiles is LowerFiles, also returning the enums the files declare.
+func lowerFiles(fset *gotoken.FileSet, paths []string, srcs [][]byte, goFiles []*goast.File, importer types.Importer) ([]*goast.File, *goast.File, []*parser.Enum, diagnostics.List) {
+	files := make([]*goast.File, len(paths))
+	constructs := parser.Constructs{}
+	diags := diagnostics.List{}
+	for i, path := range paths {
+		file, c, errs := Parse(fset, path, srcs[i])
+		files[i] = &file
+		constructs.Enums = append(constructs.Enums, c.Enums...)
+		constructs.Matches = append(constructs.Matches, c.Matches...)
+		diags = append(diags, errs...)
+	}
+	var helpers *goast.File
+	if diags.Err() == nil && len(files) > 0 {
+		var err error
+		helpers, err = lower.Package(fset, append(files[:len(files):len(files)], goFiles...), constructs, importer)
+		if list, ok := err.(diagnostics.List); ok {
+			diags = append(diags, list...)
+		}
+	}
+	diags.Sort()
+	return files, helpers, constructs.Enums, diags
+}
+
+// mergeHelpers declares the helpers in file, for it to build on its own.
+// The helpers import no package.
+func mergeHelpers(file, helpers *goast.File) {
+	file.Decls = append(file.Decls, helpers.Decls...)
+}
+
+// Translate translates the holang source src of the file at path to Go
+// source printed with config, importing packages with importer, and returns
+// it along with the map of its lines and the diagnostics found. The source
+// is nil if there is any error among them.
+func Translate(path string, src []byte, config printer.Config, importer types.Importer) ([]byte, printer.LineMap, diagnostics.List) {
+	fset := gotoken.NewFileSet()
+	file, diags := Lower(fset, path, src, importer)
+	if diags.Err() != nil {
+		return nil, nil, diags
+	}
+	gen, lines, err := config.Source(fset, file)
+	if err != nil {
+		return nil, nil, append(diags, &diagnostics.Diagnostic{Severity: diagnostics.Error, Message: err.Error()})
+	}
+	return gen, lines, diags
+}
+
+// NewImporter returns a check.Importer importing the holang packages of the
+// main module from their sources, lowered with LoadFiles.
+func NewImporter() *check.Importer {
+	imp := check.NewImporter()
+	imp.Load = func(fset *gotoken.FileSet, dir string) ([]*goast.File, []*parser.Enum, diagnostics.List) {
+		return loadFiles(fset, dir, imp)
+	}
+	return imp
+}
+
+// LoadFiles returns the syntax trees of the files of the package in dir,
+// those of the holang files lowered importing packages with importer, and
+// the file declaring the helpers they call if they call any, along with
+// every diagnostic found.
+func LoadFiles(fset *gotoken.FileSet, dir string, importer types.Importer) ([]*goast.File, diagnostics.List) {
+	files, _, diags := loadFiles(fset, dir, importer)
+	return files, diags
+}
+
+// loadFiles is LoadFiles, also returning the enums the holang files declare.
+func loadFiles(fset *gotoken.FileSet, dir string, importer types.Importer) ([]*goast.File, []*parser.Enum, diagnostics.List) {
+	pkg, err := LoadDir(dir)
+	if err != nil {
+		return nil, nil, diagnostics.List{{Severity: diagnostics.Error, Message: err.Error()}}
+	}
+	files, diags := parseGoFiles(fset, pkg)
+	paths, srcs := []string{}, [][]byte{}
+	for _, name := range pkg.HoFiles {
+		path := filepath.Join(pkg.Dir, name)
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			diags = append(diags, &diagnostics.Diagnostic{Severity: diagnostics.Error, Message: err.Error()})
+			continue
+		}
+		paths, srcs = append(paths, displayPath(path)), append(srcs, src)
+	}
+	hoFiles, helpers, enums, errs := lowerFiles(fset, paths, srcs, files, importer)
+	files = append(files, hoFiles...)
+	if helpers != nil {
+		files = append(files, helpers)
+	}
+	diags = append(diags, errs...)
+	diags.Sort()
+	return files, enums, diags
+}
+
+// parseGoFiles parses the Go files of pkg, returning those it could parse
+// along with the diagnostics of their errors.
+func parseGoFiles(fset *gotoken.FileSet, pkg *Package) ([]*goast.File, diagnostics.List) {
+	files := []*goast.File{}
+	diags := diagnostics.List{}
+	for _, name := range pkg.GoFiles {
+		file, err := goparser.ParseFile(fset, filepath.Join(pkg.Dir, name), nil, goparser.ParseComments)
+		if list, ok := err.(goscanner.ErrorList); ok {
+			for _, e := range list {
+				diags = append(diags, &diagnostics.Diagnostic{
+					Severity: diagnostics.Error,
+					Message:  e.Msg,
+					Primary:  diagnostics.Span{Start: scannerPosition(e.Pos), End: scannerPosition(e.Pos)},
+				})
+			}
+		} else if err != nil {
+			diags = append(diags, &diagnostics.Diagnostic{Severity: diagnostics.Error, Message: err.Error()})
+		}
+		if file != nil {
+			files = append(files, file)
+		}
+	}
+	return files, diags
+}
+
+// scannerPosition converts pos to the representation used by diagnostics.
+func scannerPosition(pos gotoken.Position) scanner.Position {
+	return scanner.Position{Filename: pos.Filename, Offset: pos.Offset, Line: pos.Line, Column: pos.Column}
+}
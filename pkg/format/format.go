@@ -0,0 +1,254 @@
+// Package format formats holang source.
+//
+// Since holang constructs are lowered while parsing, the formatter works on
+// the token stream rather than on the syntax tree: it keeps the line
+// structure of the source and normalizes indentation and blank lines, along
+// with the spacing around the tokens whose role does not depend on context.
+package format
+
+import (
+	"bytes"
+	gotoken "go/token"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"holang/pkg/lexer"
+	"holang/pkg/parser"
+	hotoken "holang/pkg/token"
+)
+
+// Source formats src, the content of the holang file at filepath. The
+// source must be free of syntax errors, which are returned otherwise.
+func Source(src []byte, filepath string) ([]byte, error) {
+	l := lexer.NewLexer(nil, bytes.NewReader(src), filepath)
+	p := parser.NewParser(l)
+	_, err := p.ParseFile()
+	if lexErr := l.Errors().Err(); lexErr != nil {
+		// The parser fails on illegal tokens too, with less helpful errors
+		return nil, lexErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := lexer.Tokenize(nil, bytes.NewReader(src), filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	// Like gofmt, indent with tabs and align with spaces
+	w := tabwriter.NewWriter(buf, 0, 8, 1, ' ', tabwriter.DiscardEmptyColumns|tabwriter.StripEscape)
+	f := &formatter{w: w}
+	for i, token := range tokens {
+		if token.Type == gotoken.SEMICOLON && token.Value == "\n" {
+			continue
+		}
+		f.token(token, tokens[i+1:])
+	}
+	f.write("\n")
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type formatter struct {
+	w        io.Writer
+	prev     *lexer.Token
+	last     *lexer.Token  // last token other than a comment
+	line     []lexer.Token // tokens of the current line
+	indent   int           // indentation of the current line
+	base     int           // indentation of the current line, continuation aside
+	brackets []bracket
+}
+
+type bracket struct {
+	indent int  // indentation of the lines inside the brackets
+	fields bool // whether the brackets enclose struct fields
+}
+
+// write writes s to the tabwriter, tabs separating the cells to align.
+func (f *formatter) write(s string) {
+	f.w.Write([]byte(s))
+}
+
+func (f *formatter) token(token lexer.Token, next []lexer.Token) {
+	if f.prev == nil || token.Position.Line > f.prev.End.Line {
+		f.newline(token, next)
+	} else {
+		f.space(token, next)
+	}
+	if strings.ContainsAny(token.Value, "\t\n") {
+		// Raw strings and comments are not cells
+		f.write(escape(token.Value))
+	} else {
+		f.write(token.Value)
+	}
+	f.line = append(f.line, token)
+
+	switch token.Type {
+	case gotoken.LPAREN, gotoken.LBRACK:
+		f.brackets = append(f.brackets, bracket{indent: f.indent + 1})
+	case gotoken.LBRACE:
+		// Blocks are indented from their statement, even when its header
+		// spans several lines
+		fields := f.last != nil && f.last.Type == gotoken.STRUCT
+		f.brackets = append(f.brackets, bracket{indent: f.base + 1, fields: fields})
+	case gotoken.RPAREN, gotoken.RBRACK, gotoken.RBRACE:
+		if n := len(f.brackets); n > 0 {
+			f.brackets = f.brackets[:n-1]
+		}
+	}
+	f.prev = &token
+	if token.Type != gotoken.COMMENT {
+		f.last = &token
+	}
+}
+
+// newline starts the line of token, followed by the next tokens, keeping at
+// most one blank line before it.
+func (f *formatter) newline(token lexer.Token, next []lexer.Token) {
+	if f.prev != nil {
+		f.write("\n")
+		if token.Position.Line > f.prev.End.Line+1 {
+			f.write("\n")
+		}
+	}
+	f.line = f.line[:0]
+
+	f.indent = 0
+	if n := len(f.brackets); n > 0 {
+		f.indent = f.brackets[n-1].indent
+	}
+	switch {
+	case token.Type == gotoken.RPAREN, token.Type == gotoken.RBRACK, token.Type == gotoken.RBRACE,
+		token.Type == gotoken.CASE, token.Type == gotoken.DEFAULT:
+		// Closing brackets and clauses line up with the opening line
+		f.indent--
+	case isLabel(token, next):
+		f.indent--
+	}
+	if f.indent < 0 {
+		f.indent = 0
+	}
+	f.base = f.indent
+	if f.last != nil && continues(f.last.Type) {
+		f.indent++
+	}
+	// The indentation is part of the first cell, not a cell of its own
+	f.write(escape(strings.Repeat("\t", f.indent)))
+}
+
+// space writes the spacing between the previous token and token, followed
+// by the next tokens, on the same line.
+func (f *formatter) space(token lexer.Token, next []lexer.Token) {
+	prev := *f.prev
+	spaced := token.Position.Offset > prev.End.Offset
+	switch {
+	case token.Type == gotoken.COMMENT:
+		if endsLine(token, next) {
+			// Trailing comments are aligned
+			f.write("\t")
+			return
+		}
+		spaced = true
+	case f.fieldType(token):
+		f.write("\t")
+		return
+	case token.Type == gotoken.SEMICOLON && prev.Type == gotoken.FOR:
+		spaced = true
+	case token.Type == gotoken.COMMA, token.Type == gotoken.SEMICOLON,
+		token.Type == gotoken.RPAREN, token.Type == gotoken.RBRACK,
+		token.Type == gotoken.PERIOD, token.Type == gotoken.INC, token.Type == gotoken.DEC:
+		spaced = false
+	case prev.Type == gotoken.PERIOD && token.Type == gotoken.STRING:
+		// Dot import
+		spaced = true
+	case prev.Type == gotoken.LPAREN, prev.Type == gotoken.LBRACK, prev.Type == gotoken.PERIOD:
+		spaced = false
+	case prev.Type == gotoken.COMMA, prev.Type == gotoken.SEMICOLON:
+		spaced = true
+	case spacedOperator(token.Type), spacedOperator(prev.Type):
+		spaced = true
+	}
+	if spaced {
+		f.write(" ")
+	}
+}
+
+// fieldType reports whether token starts the type of a struct field, after
+// the names of the field, which are aligned.
+func (f *formatter) fieldType(token lexer.Token) bool {
+	n := len(f.brackets)
+	if n == 0 || !f.brackets[n-1].fields || len(f.line)%2 == 0 {
+		return false
+	}
+	for i, t := range f.line {
+		if i%2 == 0 && t.Type != gotoken.IDENT || i%2 == 1 && t.Type != gotoken.COMMA {
+			return false
+		}
+	}
+	switch token.Type {
+	case gotoken.COMMA, gotoken.PERIOD, gotoken.STRING, gotoken.COMMENT, gotoken.SEMICOLON:
+		return false
+	}
+	return true
+}
+
+// escape escapes s from the tabwriter, so that its tabs do not end cells.
+func escape(s string) string {
+	return string([]byte{tabwriter.Escape}) + s + string([]byte{tabwriter.Escape})
+}
+
+// endsLine reports whether token, followed by the next tokens, is the last
+// of its line.
+func endsLine(token lexer.Token, next []lexer.Token) bool {
+	for _, t := range next {
+		if t.Type == gotoken.SEMICOLON && t.Value == "\n" {
+			continue
+		}
+		return t.Position.Line > token.End.Line
+	}
+	return true
+}
+
+// spacedOperator reports whether t is an operator always surrounded by
+// spaces: one that can only be binary.
+func spacedOperator(t gotoken.Token) bool {
+	switch t {
+	case gotoken.ASSIGN, gotoken.DEFINE, gotoken.ADD_ASSIGN, gotoken.SUB_ASSIGN,
+		gotoken.MUL_ASSIGN, gotoken.QUO_ASSIGN, gotoken.REM_ASSIGN, gotoken.AND_ASSIGN,
+		gotoken.OR_ASSIGN, gotoken.XOR_ASSIGN, gotoken.SHL_ASSIGN, gotoken.SHR_ASSIGN,
+		gotoken.AND_NOT_ASSIGN, hotoken.POW_ASSIGN, hotoken.FAT_ARROW,
+		gotoken.EQL, gotoken.NEQ, gotoken.LSS, gotoken.LEQ, gotoken.GTR, gotoken.GEQ,
+		gotoken.LAND, gotoken.LOR:
+		return true
+	}
+	return false
+}
+
+// isLabel reports whether token, followed by the next tokens, is a label
+// alone on its line.
+func isLabel(token lexer.Token, next []lexer.Token) bool {
+	if token.Type != gotoken.IDENT || len(next) == 0 || next[0].Type != gotoken.COLON {
+		return false
+	}
+	if len(next) == 1 {
+		return true
+	}
+	after := next[1]
+	return after.Type == gotoken.COMMENT || after.Type == gotoken.SEMICOLON && after.Value == "\n" ||
+		after.Position.Line > next[0].End.Line
+}
+
+// continues reports whether a line ending with t continues on the next line:
+// whether t is a binary operator.
+func continues(t gotoken.Token) bool {
+	switch t {
+	case gotoken.LPAREN, gotoken.LBRACK, gotoken.LBRACE, gotoken.RPAREN, gotoken.RBRACK, gotoken.RBRACE,
+		gotoken.COMMA, gotoken.SEMICOLON, gotoken.COLON, gotoken.INC, gotoken.DEC, gotoken.PERIOD, gotoken.ELLIPSIS:
+		return false
+	}
+	return t.IsOperator() || t == hotoken.POW || t == hotoken.FAT_ARROW
+}
@@ -0,0 +1,78 @@
+package format_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"holang/pkg/format"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			"indentation",
+			"package p\nfunc f() {\n  if true {\n        return\n  }\n}\n",
+			"package p\nfunc f() {\n\tif true {\n\t\treturn\n\t}\n}\n",
+		},
+		{
+			"blank lines",
+			"package p\n\n\n\nvar x = 1\n\n\n",
+			"package p\n\nvar x = 1\n",
+		},
+		{
+			"operators",
+			"package p\n\nvar b = 1==2||x>=y\n",
+			"package p\n\nvar b = 1 == 2 || x >= y\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := format.Source([]byte(test.src), "p.ho")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("got\n%s\nwant\n%s", got, test.want)
+			}
+			if again, err := format.Source(got, "p.ho"); err != nil || string(again) != string(got) {
+				t.Errorf("formatting again gives\n%s", again)
+			}
+		})
+	}
+}
+
+// TestIdempotence checks that formatting the samples free of syntax errors
+// twice gives the same source as formatting them once.
+func TestIdempotence(t *testing.T) {
+	names, err := filepath.Glob(filepath.Join("..", "..", "samples", "*.ho"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) == 0 {
+		t.Fatal("no samples")
+	}
+	for _, name := range names {
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			src, err := ioutil.ReadFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			once, err := format.Source(src, name)
+			if err != nil {
+				t.Skip("syntax errors")
+			}
+			twice, err := format.Source(once, name)
+			if err != nil {
+				t.Fatalf("formatted source has errors: %v\n%s", err, once)
+			}
+			if string(twice) != string(once) {
+				t.Errorf("formatting again gives\n%s\nformatted once\n%s", twice, once)
+			}
+		})
+	}
+}
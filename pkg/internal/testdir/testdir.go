@@ -0,0 +1,25 @@
+// Package testdir lays out the files of the packages used by tests.
+package testdir
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Write writes files, contents by slash-separated path, to a new temporary
+// directory removed after the test, and returns it.
+func Write(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
@@ -0,0 +1,102 @@
+package lexer
+
+import (
+	"holang/pkg/diagnostics"
+)
+
+// ErrorKind describes why a token is illegal.
+type ErrorKind string
+
+const (
+	InvalidCharacter      ErrorKind = "invalid character"
+	InvalidBinaryLiteral  ErrorKind = "binary literal has no digits"
+	InvalidOctalLiteral   ErrorKind = "invalid octal literal"
+	InvalidHexLiteral     ErrorKind = "hexadecimal literal has no digits"
+	IncompleteEllipsis    ErrorKind = "incomplete ellipsis"
+	EmptyRune             ErrorKind = "empty rune literal"
+	MultiCharRune         ErrorKind = "more than one character in rune literal"
+	UnterminatedRune      ErrorKind = "unterminated rune literal"
+	UnterminatedString    ErrorKind = "unterminated string"
+	UnterminatedRawString ErrorKind = "unterminated raw string"
+	UnterminatedComment   ErrorKind = "unterminated comment"
+	InvalidBinaryDigit    ErrorKind = "invalid digit in binary literal"
+	InvalidRadixPoint     ErrorKind = "invalid radix point in binary or octal literal"
+	InvalidExponent       ErrorKind = "exponent does not match the base of the literal"
+	MissingExponentDigits ErrorKind = "exponent has no digits"
+	MissingHexExponent    ErrorKind = "hexadecimal mantissa requires a 'p' exponent"
+	InvalidDigitSeparator ErrorKind = "'_' must separate successive digits"
+	InvalidEncoding       ErrorKind = "invalid UTF-8 encoding"
+	UnknownEscape         ErrorKind = "unknown escape sequence"
+	InvalidEscape         ErrorKind = "invalid character in escape sequence"
+	InvalidCodePoint      ErrorKind = "escape sequence is invalid Unicode code point"
+)
+
+var codes = map[ErrorKind]string{
+	InvalidCharacter:      "L0001",
+	InvalidBinaryLiteral:  "L0002",
+	InvalidOctalLiteral:   "L0003",
+	InvalidHexLiteral:     "L0004",
+	IncompleteEllipsis:    "L0005",
+	EmptyRune:             "L0006",
+	MultiCharRune:         "L0007",
+	UnterminatedRune:      "L0008",
+	UnterminatedString:    "L0009",
+	UnterminatedRawString: "L0010",
+	UnterminatedComment:   "L0011",
+	InvalidBinaryDigit:    "L0012",
+	InvalidRadixPoint:     "L0013",
+	InvalidExponent:       "L0014",
+	MissingExponentDigits: "L0015",
+	MissingHexExponent:    "L0016",
+	InvalidDigitSeparator: "L0017",
+	InvalidEncoding:       "L0018",
+	UnknownEscape:         "L0019",
+	InvalidEscape:         "L0020",
+	InvalidCodePoint:      "L0021",
+}
+
+// Code returns the diagnostic code of the kind.
+func (k ErrorKind) Code() string {
+	return codes[k]
+}
+
+func newDiagnostic(t Token) *diagnostics.Diagnostic {
+	d := &diagnostics.Diagnostic{
+		Severity: diagnostics.Error,
+		Code:     t.illegal.Code(),
+		Message:  string(t.illegal),
+		Primary:  diagnostics.Span{Start: t.Position, End: t.End},
+	}
+	insert := func(message, text string) {
+		d.Fixes = append(d.Fixes, diagnostics.Fix{
+			Message:     message,
+			Span:        diagnostics.Span{Start: t.End, End: t.End},
+			Replacement: text,
+		})
+	}
+	switch t.illegal {
+	case InvalidOctalLiteral:
+		d.Notes = append(d.Notes, "octal literals only use the digits 0 to 7")
+	case InvalidBinaryDigit:
+		d.Notes = append(d.Notes, "binary literals only use the digits 0 and 1")
+	case InvalidExponent:
+		d.Notes = append(d.Notes, "decimal literals take an 'e' exponent, hexadecimal ones a 'p' exponent")
+	case UnknownEscape:
+		d.Notes = append(d.Notes, `the escape sequences are \a, \b, \f, \n, \r, \t, \v, \\, the quote and the octal, \x, \u and \U ones`)
+	case InvalidEscape:
+		d.Notes = append(d.Notes, `octal escapes take 3 digits, \x escapes 2 hexadecimal digits, \u escapes 4 and \U escapes 8`)
+	case InvalidCodePoint:
+		d.Notes = append(d.Notes, "octal and \\x escapes are at most 255, \\u and \\U escapes at most 10FFFF and not surrogate halves")
+	case IncompleteEllipsis:
+		insert("complete the ellipsis", ".")
+	case UnterminatedRune:
+		insert("add the closing quote", "'")
+	case UnterminatedString:
+		insert("add the closing quote", `"`)
+	case UnterminatedRawString:
+		insert("add the closing backquote", "`")
+	case UnterminatedComment:
+		insert("close the comment", "*/")
+	}
+	return d
+}
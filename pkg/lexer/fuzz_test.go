@@ -0,0 +1,81 @@
+package lexer_test
+
+import (
+	"bytes"
+	gotoken "go/token"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"holang/pkg/lexer"
+	hotoken "holang/pkg/token"
+)
+
+// addSamples seeds f with the samples of the repository.
+func addSamples(f *testing.F) {
+	for _, pattern := range []string{"*.ho", "*.go"} {
+		paths, err := filepath.Glob(filepath.Join("..", "..", "samples", pattern))
+		if err != nil {
+			f.Fatal(err)
+		}
+		for _, path := range paths {
+			src, err := ioutil.ReadFile(path)
+			if err != nil {
+				f.Fatal(err)
+			}
+			f.Add(src)
+		}
+	}
+}
+
+// FuzzTokenize checks that the lexer does not panic and that, on inputs
+// without errors, the values of the tokens separated by whitespace lex to
+// the same tokens.
+func FuzzTokenize(f *testing.F) {
+	addSamples(f)
+	f.Add([]byte("x := 0x1p-2 + 1_000i // end"))
+	f.Add([]byte("c := '\\x41' + '\\u00e9' /* a\nb */ s := `raw\\`"))
+	f.Fuzz(func(t *testing.T, src []byte) {
+		tokens, err := lexer.Tokenize(nil, bytes.NewReader(src), "fuzz.ho")
+		if err != nil {
+			return
+		}
+		var joined strings.Builder
+		for _, token := range tokens {
+			joined.WriteString(token.Value)
+			// A line comment runs to the end of the line
+			if strings.HasPrefix(token.Value, "//") {
+				joined.WriteString("\n")
+			} else {
+				joined.WriteString(" ")
+			}
+		}
+		again, err := lexer.Tokenize(nil, strings.NewReader(joined.String()), "fuzz.ho")
+		if err != nil {
+			t.Fatalf("%q lexes with errors: %v", joined.String(), err)
+		}
+		got, want := explicit(again), explicit(tokens)
+		for i := 0; i < len(got) && i < len(want); i++ {
+			if got[i].Type != want[i].Type || got[i].Value != want[i].Value {
+				t.Fatalf("token %d of %q is %s %q, want %s %q", i, joined.String(),
+					hotoken.String(got[i].Type), got[i].Value, hotoken.String(want[i].Type), want[i].Value)
+			}
+		}
+		if len(got) != len(want) {
+			t.Fatalf("%q lexes to %d tokens, want %d", joined.String(), len(got), len(want))
+		}
+	})
+}
+
+// explicit returns tokens without the semicolons inserted at the end of
+// lines, which depend on the layout.
+func explicit(tokens []lexer.Token) []lexer.Token {
+	out := []lexer.Token{}
+	for _, token := range tokens {
+		if token.Type != gotoken.SEMICOLON || token.Value != "\n" {
+			out = append(out, token)
+		}
+	}
+	return out
+}
@@ -0,0 +1,148 @@
+package lexer_test
+
+import (
+	"bytes"
+	"fmt"
+	"go/build"
+	goscanner "go/scanner"
+	gotoken "go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"holang/pkg/lexer"
+	hotoken "holang/pkg/token"
+)
+
+// TestGoScanner checks that the lexer agrees with go/scanner on every Go
+// file of the standard library, holang being a superset of Go: same types,
+// values and positions, the semicolons inserted at the end of lines
+// included. It reports the first divergence of each file.
+func TestGoScanner(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the standard library in short mode")
+	}
+	root := filepath.Join(build.Default.GOROOT, "src")
+	if _, err := os.Stat(root); err != nil {
+		t.Skipf("no standard library: %v", err)
+	}
+	files := 0
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			// Test data holds invalid files on purpose
+			if info.Name() == "testdata" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		want, ok := goTokens(path, src)
+		if !ok {
+			return nil
+		}
+		files++
+		got, _ := lexer.Tokenize(nil, bytes.NewReader(src), path)
+		if d := divergence(got, want); d != "" {
+			t.Error(d)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files == 0 {
+		t.Fatalf("no Go files in %s", root)
+	}
+}
+
+// tok is a token of go/scanner.
+type tok struct {
+	pos   gotoken.Position
+	typ   gotoken.Token
+	value string
+}
+
+// goTokens returns the tokens of the Go file src as the lexer should return
+// them, with ok false if go/scanner finds errors in it.
+func goTokens(path string, src []byte) (toks []tok, ok bool) {
+	fset := gotoken.NewFileSet()
+	file := fset.AddFile(path, -1, len(src))
+	errs := 0
+	var s goscanner.Scanner
+	s.Init(file, src, func(gotoken.Position, string) { errs++ }, goscanner.ScanComments)
+	for {
+		pos, typ, lit := s.Scan()
+		if typ == gotoken.EOF {
+			break
+		}
+		if lit == "" {
+			lit = typ.String()
+		}
+		toks = append(toks, tok{fset.Position(pos), typ, lit})
+	}
+	return holang(toks), errs == 0
+}
+
+// holang returns toks as holang sees them: a pair of adjacent asterisks is a
+// power operator, and match and enum are keywords, which end no line.
+func holang(toks []tok) []tok {
+	out := []tok{}
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		if t.typ == gotoken.MUL && i+1 < len(toks) && toks[i+1].pos.Offset == t.pos.Offset+1 {
+			switch toks[i+1].typ {
+			case gotoken.MUL:
+				t.typ, t.value = hotoken.POW, "**"
+				i++
+			case gotoken.MUL_ASSIGN:
+				t.typ, t.value = hotoken.POW_ASSIGN, "**="
+				i++
+			}
+		}
+		if t.typ == gotoken.IDENT && (t.value == "match" || t.value == "enum") {
+			t.typ = hotoken.MATCH
+			if t.value == "enum" {
+				t.typ = hotoken.ENUM
+			}
+			if i+1 < len(toks) && toks[i+1].typ == gotoken.SEMICOLON && toks[i+1].value == "\n" {
+				i++
+			}
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// divergence describes the first token of got differing from want, if any.
+// Columns are not compared: those of the lexer count characters, those of
+// go/scanner bytes.
+func divergence(got []lexer.Token, want []tok) string {
+	for i := 0; i < len(got) || i < len(want); i++ {
+		switch {
+		case i == len(want):
+			g := got[i]
+			return fmt.Sprintf("%s: got %s %q, want EOF", g.Position, hotoken.String(g.Type), g.Value)
+		case i == len(got):
+			w := want[i]
+			return fmt.Sprintf("%s: got EOF, want %s %q", w.pos, hotoken.String(w.typ), w.value)
+		}
+		g, w := got[i], want[i]
+		if g.Type != w.typ || g.Value != w.value || g.Position.Offset != w.pos.Offset || g.Position.Line != w.pos.Line {
+			return fmt.Sprintf("%s: got %s %q at offset %d, want %s %q at offset %d",
+				w.pos, hotoken.String(g.Type), g.Value, g.Position.Offset,
+				hotoken.String(w.typ), w.value, w.pos.Offset)
+		}
+	}
+	return ""
+}
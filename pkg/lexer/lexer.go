@@ -1,197 +1,300 @@
 package lexer
 
 import (
-	"fmt"
 	gotoken "go/token"
 	"io"
+	"strings"
 	"text/scanner"
 	"unicode"
+	"unicode/utf8"
 
+	"holang/pkg/diagnostics"
 	"holang/pkg/token"
 )
 
 type Token struct {
 	Type     token.Token
-	Position scanner.Position
+	Position scanner.Position // position of the first character of the token
+	End      scanner.Position // position immediately after the last character of the token
+	Pos      gotoken.Pos      // Position mapped into the token.FileSet given to the lexer
 	Value    string
+	illegal  ErrorKind // why the token is ILLEGAL
+	char     rune      // value of a CHAR token
+	str      string    // value of a STRING token
 }
 
 func (t *Token) IsBlankIdentifier() bool {
 	return t.Type == gotoken.IDENT && t.Value == "_"
 }
 
-func Tokenize(reader io.Reader, filepath string) ([]Token, error) {
-	tokens := []Token{}
+// Rune returns the value of a CHAR token, its escape sequence decoded.
+func (t *Token) Rune() rune {
+	return t.char
+}
+
+// Unquoted returns the value of a STRING token, its escape sequences
+// decoded.
+func (t *Token) Unquoted() string {
+	return t.str
+}
+
+// EndPos returns the token.Pos immediately after the last character of the
+// token.
+func (t *Token) EndPos() gotoken.Pos {
+	if !t.Pos.IsValid() {
+		return gotoken.NoPos
+	}
+	return t.Pos + gotoken.Pos(t.End.Offset-t.Position.Offset)
+}
+
+// Lexer turns its input into tokens on demand, reading only as much of the
+// input as needed to produce the next token.
+type Lexer struct {
+	scanner  scanner.Scanner
+	source   *sourceReader
+	fset     *gotoken.FileSet
+	filepath string
+	base     int
+	asi      bool // automatic semicolon insertion on upcoming newline?
+	eof      *Token
+	pending  *Token // token read ahead to insert a semicolon before it
+	errors   diagnostics.List
+}
+
+// NewLexer returns a lexer reading from reader. When fset is not nil, the
+// file is registered in it once the end of the input has been reached, and
+// every token carries a Pos valid in that set. Lexing several files
+// concurrently against the same fset is not supported.
+func NewLexer(fset *gotoken.FileSet, reader io.Reader, filepath string) *Lexer {
+	l := &Lexer{
+		source:   &sourceReader{reader: reader, lines: []int{0}},
+		fset:     fset,
+		filepath: filepath,
+		base:     -1,
+	}
+	l.scanner.Init(l.source)
+	l.scanner.Filename = filepath
+	l.scanner.Error = l.scannerError
+	if fset != nil {
+		l.base = fset.Base()
+	}
+	return l
+}
+
+// Next returns the next token of the input. Once the end of the input has
+// been reached, it keeps returning the same EOF token.
+func (l *Lexer) Next() Token {
+	if l.eof != nil {
+		return *l.eof
+	}
+
+	var t Token
+	if l.pending != nil {
+		t, l.pending = *l.pending, nil
+	} else {
+		t = readToken(&l.scanner, l.asi)
+		if l.base >= 0 {
+			t.Pos = gotoken.Pos(l.base + t.Position.Offset)
+		}
+	}
+
+	// The end of the input acts like a newline: the semicolon goes before it
+	if l.asi && t.Type == gotoken.EOF {
+		l.asi = false
+		l.pending = &t
+		return Token{Type: gotoken.SEMICOLON, Position: t.Position, End: t.Position, Pos: t.Pos, Value: "\n"}
+	}
+	// So does a comment spanning lines, like go/scanner the semicolon
+	// follows it, at its first newline
+	if l.asi && t.Type == gotoken.COMMENT && strings.Contains(t.Value, "\n") {
+		l.asi = false
+		l.pending = newlineSemicolon(t)
+		return t
+	}
+
+	asi := l.asi
+	l.asi = false
+	switch t.Type {
+	case gotoken.IDENT:
+		fallthrough
+	case gotoken.INT:
+		fallthrough
+	case gotoken.FLOAT:
+		fallthrough
+	case gotoken.IMAG:
+		fallthrough
+	case gotoken.CHAR:
+		fallthrough
+	case gotoken.STRING:
+		fallthrough
+	case gotoken.BREAK:
+		fallthrough
+	case gotoken.CONTINUE:
+		fallthrough
+	case gotoken.FALLTHROUGH:
+		fallthrough
+	case gotoken.RETURN:
+		fallthrough
+	case gotoken.INC:
+		fallthrough
+	case gotoken.DEC:
+		fallthrough
+	case gotoken.RPAREN:
+		fallthrough
+	case gotoken.RBRACK:
+		fallthrough
+	case gotoken.RBRACE:
+		l.asi = true
+	case gotoken.ILLEGAL:
+		// Illegal tokens mostly stand for malformed literals, so behave like
+		// them to keep the following lines well delimited
+		l.asi = true
+		if !l.reported(t.Position) {
+			l.errors = append(l.errors, newDiagnostic(t))
+		}
+	case gotoken.COMMENT:
+		// A comment within a line does not end the previous token
+		l.asi = asi
+	case gotoken.EOF:
+		l.eof = &t
+		l.register()
+	}
+	return t
+}
+
+// Errors returns a diagnostic for every illegal token met so far.
+func (l *Lexer) Errors() diagnostics.List {
+	return l.errors
+}
+
+// newlineSemicolon returns the semicolon inserted at the first newline of
+// comment.
+func newlineSemicolon(comment Token) *Token {
+	i := strings.Index(comment.Value, "\n")
+	start := comment.Position
+	start.Offset += i
+	start.Column += utf8.RuneCountInString(comment.Value[:i])
+	end := scanner.Position{Filename: start.Filename, Offset: start.Offset + 1, Line: start.Line + 1, Column: 1}
+	t := &Token{Type: gotoken.SEMICOLON, Position: start, End: end, Value: "\n"}
+	if comment.Pos.IsValid() {
+		t.Pos = comment.Pos + gotoken.Pos(i)
+	}
+	return t
+}
 
-	s := scanner.Scanner{}
-	s.Init(reader)
-	s.Filename = filepath
+// scannerError reports the invalid characters the scanner meets: NUL and
+// invalid UTF-8 encodings, wherever they are.
+func (l *Lexer) scannerError(s *scanner.Scanner, msg string) {
+	kind := InvalidCharacter
+	if strings.Contains(msg, "UTF-8") {
+		kind = InvalidEncoding
+	}
+	start := s.Pos()
+	end := start
+	end.Offset++
+	end.Column++
+	l.errors = append(l.errors, newDiagnostic(Token{Position: start, End: end, illegal: kind}))
+}
+
+// reported reports whether an error was reported at pos.
+func (l *Lexer) reported(pos scanner.Position) bool {
+	for i := len(l.errors) - 1; i >= 0; i-- {
+		if l.errors[i].Primary.Start.Offset == pos.Offset {
+			return true
+		}
+	}
+	return false
+}
 
-	asi := false // automatic semicolon insertion on upcoming newline?
+func (l *Lexer) register() {
+	if l.fset == nil {
+		return
+	}
+	lines := l.source.lines
+	if n := len(lines); lines[n-1] == l.source.size && n > 1 {
+		lines = lines[:n-1] // a trailing newline does not start a line
+	}
+	l.fset.AddFile(l.filepath, l.base, l.source.size).SetLines(lines)
+}
+
+// Tokenize reads the whole input and returns its tokens, illegal ones
+// included, along with a diagnostics.List describing every illegal token. See
+// NewLexer for the meaning of fset.
+func Tokenize(fset *gotoken.FileSet, reader io.Reader, filepath string) ([]Token, error) {
+	tokens := []Token{}
+	l := NewLexer(fset, reader, filepath)
 	for {
-		t := readToken(&s, asi)
-		asi = false
-		switch t.Type {
-		case gotoken.IDENT:
-			fallthrough
-		case gotoken.INT:
-			fallthrough
-		case gotoken.FLOAT:
-			fallthrough
-		case gotoken.IMAG:
-			fallthrough
-		case gotoken.CHAR:
-			fallthrough
-		case gotoken.STRING:
-			fallthrough
-		case gotoken.BREAK:
-			fallthrough
-		case gotoken.CONTINUE:
-			fallthrough
-		case gotoken.FALLTHROUGH:
-			fallthrough
-		case gotoken.RETURN:
-			fallthrough
-		case gotoken.INC:
-			fallthrough
-		case gotoken.DEC:
-			fallthrough
-		case gotoken.RPAREN:
-			fallthrough
-		case gotoken.RBRACK:
-			fallthrough
-		case gotoken.RBRACE:
-			asi = true
-			fallthrough
-		default:
-			tokens = append(tokens, t)
-		case gotoken.ILLEGAL:
-			return tokens, fmt.Errorf("%+v", t)
-		case gotoken.EOF:
-			return tokens, nil
+		t := l.Next()
+		if t.Type == gotoken.EOF {
+			return tokens, l.Errors().Err()
 		}
+		tokens = append(tokens, t)
 	}
 }
 
-func readToken(s *scanner.Scanner, asi bool) Token {
-	v := []rune{}
+// sourceReader records the size of the input and the offset of every line
+// as the scanner consumes it, which is what a token.File needs to map
+// offsets to lines.
+type sourceReader struct {
+	reader io.Reader
+	size   int
+	lines  []int
+}
+
+func (r *sourceReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	for i, b := range p[:n] {
+		if b == '\n' {
+			r.lines = append(r.lines, r.size+i+1)
+		}
+	}
+	r.size += n
+	return n, err
+}
 
+func readToken(s *scanner.Scanner, asi bool) Token {
 	for isWhitespace(s.Peek()) || isNewline(s.Peek()) {
+		start := s.Pos()
 		cur := s.Next()
 		if asi && isNewline(cur) {
-			return Token{Type: gotoken.SEMICOLON}
+			// The inserted semicolon spans the newline that triggered it
+			return Token{Type: gotoken.SEMICOLON, Position: start, End: s.Pos(), Value: "\n"}
 		}
 	}
 
+	start := s.Pos()
+	t := scanToken(s)
+	t.Position = start
+	t.End = s.Pos()
+	return t
+}
+
+func scanToken(s *scanner.Scanner) Token {
+	v := []rune{}
+
 	if s.Peek() == scanner.EOF {
 		return Token{Type: gotoken.EOF}
 	}
 
-	if isDecimalDigit(s.Peek()) || s.Peek() == '.' {
+	if s.Peek() == '.' {
 		v = append(v, s.Next())
-		// Binary notation 0b...
-		if v[0] == '0' && (s.Peek() == 'B' || s.Peek() == 'b') {
-			v = append(v, s.Next())
-			if isBinaryDigit(s.Peek()) {
-				v = append(v, s.Next())
-				for isBinaryDigit(s.Peek()) || isDigitSeparator(s.Peek()) {
-					v = append(v, s.Next())
-				}
-				return Token{Type: gotoken.INT, Value: string(v)}
-			}
-			return Token{Type: gotoken.ILLEGAL, Value: string(v)}
-		}
-		// Octal notation 0o...
-		if v[0] == '0' && (s.Peek() == 'O' || s.Peek() == 'o') {
-			v = append(v, s.Next())
-			if isOctalDigit(s.Peek()) {
-				v = append(v, s.Next())
-				for isOctalDigit(s.Peek()) || isDigitSeparator(s.Peek()) {
-					v = append(v, s.Next())
-				}
-				return Token{Type: gotoken.INT, Value: string(v)}
-			}
-			return Token{Type: gotoken.ILLEGAL, Value: string(v)}
-		}
-		// Hexadecimal notation 0x...
-		if v[0] == '0' && (s.Peek() == 'X' || s.Peek() == 'x') {
-			v = append(v, s.Next())
-			if isHexDigit(s.Peek()) {
-				v = append(v, s.Next())
-				for isHexDigit(s.Peek()) || isDigitSeparator(s.Peek()) {
-					v = append(v, s.Next())
-				}
-				return Token{Type: gotoken.INT, Value: string(v)}
-			}
-			return Token{Type: gotoken.ILLEGAL, Value: string(v)}
-		}
-		// Alternative octal notation 0...
-		if v[0] == '0' {
-			if isOctalDigit(s.Peek()) {
-				v = append(v, s.Next())
-				for isOctalDigit(s.Peek()) || isDigitSeparator(s.Peek()) {
-					v = append(v, s.Next())
-				}
-			}
-			if !(isDecimalDigit(s.Peek()) || isDigitSeparator(s.Peek()) || s.Peek() == '.' || s.Peek() == 'i' || s.Peek() == 'E' || s.Peek() == 'e') {
-				return Token{Type: gotoken.INT, Value: string(v)}
-			}
-			// fallthrough
-		}
-		// Dealing with decimals, floating-point and imaginary numbers
-		float := false
-		if isDecimalDigit(v[0]) {
-			for isDecimalDigit(s.Peek()) || isDigitSeparator(s.Peek()) {
-				v = append(v, s.Next())
-			}
-		}
-		if (v[0] == '.' || s.Peek() == '.') && v[0] != s.Peek() {
-			float = true
-			if s.Peek() == '.' {
-				v = append(v, s.Next())
-			}
-			if isDecimalDigit(s.Peek()) {
-				v = append(v, s.Next())
-				for isDecimalDigit(s.Peek()) || isDigitSeparator(s.Peek()) {
-					v = append(v, s.Next())
-				}
-			}
+		// A period only starts a number when followed by a digit
+		if isDecimalDigit(s.Peek()) {
+			return scanNumber(s, v)
 		}
-		if s.Peek() == 'E' || s.Peek() == 'e' {
-			float = true
+		if s.Peek() == '.' {
 			v = append(v, s.Next())
-			if s.Peek() == '+' || s.Peek() == '-' {
-				v = append(v, s.Next())
-			}
-			if isDecimalDigit(s.Peek()) {
-				v = append(v, s.Next())
-				for isDecimalDigit(s.Peek()) || isDigitSeparator(s.Peek()) {
-					v = append(v, s.Next())
-				}
-			}
-		}
-		if s.Peek() == 'i' {
-			v = append(v, s.Next())
-			return Token{Type: gotoken.IMAG, Value: string(v)}
-		}
-		if v[0] == '.' {
 			if s.Peek() == '.' {
 				v = append(v, s.Next())
-				if s.Peek() == '.' {
-					v = append(v, s.Next())
-					return Token{Type: gotoken.ELLIPSIS, Value: string(v)}
-				}
-				return Token{Type: gotoken.ILLEGAL, Value: string(v)}
+				return Token{Type: gotoken.ELLIPSIS, Value: string(v)}
 			}
-			return Token{Type: gotoken.PERIOD, Value: string(v)}
+			return illegal(IncompleteEllipsis, v)
 		}
-		if float {
-			return Token{Type: gotoken.FLOAT, Value: string(v)}
-		}
-		if v[0] == '0' {
-			return Token{Type: gotoken.ILLEGAL, Value: string(v)}
-		}
-		return Token{Type: gotoken.INT, Value: string(v)}
+		return Token{Type: gotoken.PERIOD, Value: string(v)}
+	}
+
+	if isDecimalDigit(s.Peek()) {
+		return scanNumber(s, v)
 	}
 
 	if isLetter(s.Peek()) {
@@ -260,50 +363,15 @@ func readToken(s *scanner.Scanner, asi bool) Token {
 	}
 
 	if s.Peek() == '\'' {
-		v = append(v, s.Next())
-		if s.Peek() == '\\' {
-			v = append(v, s.Next())
-		}
-		v = append(v, s.Next())
-		if s.Peek() == '\'' {
-			v = append(v, s.Next())
-			return Token{Type: gotoken.CHAR, Value: string(v)}
-		}
-		return Token{Type: gotoken.ILLEGAL, Value: string(v)}
+		return scanRune(s)
 	}
 
 	if s.Peek() == '"' {
-		v = append(v, s.Next())
-		escaped := false
-		for !isNewline(s.Peek()) {
-			cur := s.Next()
-			v = append(v, cur)
-			if escaped {
-				escaped = false
-			} else if cur == '\\' {
-				escaped = true
-			} else if cur == '"' {
-				return Token{Type: gotoken.STRING, Value: string(v)}
-			}
-		}
-		return Token{Type: gotoken.ILLEGAL, Value: string(v)}
+		return scanString(s)
 	}
 
 	if s.Peek() == '`' {
-		v = append(v, s.Next())
-		escaped := false
-		for s.Peek() != scanner.EOF {
-			cur := s.Next()
-			v = append(v, cur)
-			if escaped {
-				escaped = false
-			} else if cur == '\\' {
-				escaped = true
-			} else if cur == '`' {
-				return Token{Type: gotoken.STRING, Value: string(v)}
-			}
-		}
-		return Token{Type: gotoken.ILLEGAL, Value: string(v)}
+		return scanRawString(s)
 	}
 
 	if s.Peek() == '+' {
@@ -353,7 +421,7 @@ func readToken(s *scanner.Scanner, asi bool) Token {
 		v = append(v, s.Next())
 		if s.Peek() == '/' {
 			v = append(v, s.Next())
-			for !isNewline(s.Peek()) {
+			for !isNewline(s.Peek()) && s.Peek() != scanner.EOF {
 				v = append(v, s.Next())
 			}
 			return Token{Type: gotoken.COMMENT, Value: string(v)}
@@ -368,7 +436,7 @@ func readToken(s *scanner.Scanner, asi bool) Token {
 					return Token{Type: gotoken.COMMENT, Value: string(v)}
 				}
 			}
-			return Token{Type: gotoken.ILLEGAL, Value: string(v)}
+			return illegal(UnterminatedComment, v)
 		}
 		if s.Peek() == '=' {
 			v = append(v, s.Next())
@@ -473,6 +541,10 @@ func readToken(s *scanner.Scanner, asi bool) Token {
 			v = append(v, s.Next())
 			return Token{Type: gotoken.EQL, Value: string(v)}
 		}
+		if s.Peek() == '>' {
+			v = append(v, s.Next())
+			return Token{Type: token.FAT_ARROW, Value: string(v)}
+		}
 		return Token{Type: gotoken.ASSIGN, Value: string(v)}
 	}
 
@@ -494,6 +566,11 @@ func readToken(s *scanner.Scanner, asi bool) Token {
 		return Token{Type: gotoken.COLON, Value: string(v)}
 	}
 
+	if s.Peek() == '~' {
+		v = append(v, s.Next())
+		return Token{Type: gotoken.TILDE, Value: string(v)}
+	}
+
 	if s.Peek() == '(' {
 		v = append(v, s.Next())
 		return Token{Type: gotoken.LPAREN, Value: string(v)}
@@ -535,7 +612,347 @@ func readToken(s *scanner.Scanner, asi bool) Token {
 	}
 
 	v = append(v, s.Next())
-	return Token{Type: gotoken.ILLEGAL, Value: string(v)}
+	return illegal(InvalidCharacter, v)
+}
+
+// scanNumber scans an integer, floating-point or imaginary literal, v being
+// its leading period if any. Like go/scanner, it reads every digit and
+// separator of the literal before checking them, so that an invalid literal
+// makes a single illegal token.
+func scanNumber(s *scanner.Scanner, v []rune) Token {
+	typ := gotoken.INT
+	base, prefix := 10, rune(0) // prefix is 0 for decimal literals, '0' for 0-prefixed octal ones
+	invalid := false            // a digit is out of the base
+	var kind ErrorKind          // first error met
+	fail := func(k ErrorKind) {
+		if kind == "" {
+			kind = k
+		}
+	}
+	digits := func(base int) bool {
+		read := false
+		for {
+			r := s.Peek()
+			switch {
+			case isDigitSeparator(r):
+			case base == 16 && isHexDigit(r), base <= 10 && isDecimalDigit(r):
+				read = true
+				if base < 10 && r >= '0'+rune(base) {
+					invalid = true
+				}
+			default:
+				return read
+			}
+			v = append(v, s.Next())
+		}
+	}
+
+	mantissa := len(v) > 0 // the leading period makes a digit of the mantissa unnecessary
+	if len(v) == 0 {
+		if s.Peek() == '0' {
+			v = append(v, s.Next())
+			switch lower(s.Peek()) {
+			case 'x':
+				base, prefix = 16, 'x'
+			case 'o':
+				base, prefix = 8, 'o'
+			case 'b':
+				base, prefix = 2, 'b'
+			default:
+				base, prefix = 8, '0'
+				mantissa = true
+			}
+			if prefix != '0' {
+				v = append(v, s.Next())
+			}
+		}
+		if digits(base) {
+			mantissa = true
+		}
+		if s.Peek() == '.' {
+			if prefix == 'o' || prefix == 'b' {
+				fail(InvalidRadixPoint)
+			}
+			v = append(v, s.Next())
+		}
+	}
+	if v[len(v)-1] == '.' {
+		typ = gotoken.FLOAT
+		if digits(base) {
+			mantissa = true
+		}
+	}
+	if !mantissa {
+		switch prefix {
+		case 'x':
+			fail(InvalidHexLiteral)
+		case 'o':
+			fail(InvalidOctalLiteral)
+		case 'b':
+			fail(InvalidBinaryLiteral)
+		}
+	}
+
+	if r := lower(s.Peek()); r == 'e' || r == 'p' {
+		if r == 'e' && prefix != 0 && prefix != '0' || r == 'p' && prefix != 'x' {
+			fail(InvalidExponent)
+		}
+		v = append(v, s.Next())
+		typ = gotoken.FLOAT
+		if s.Peek() == '+' || s.Peek() == '-' {
+			v = append(v, s.Next())
+		}
+		if !digits(10) {
+			fail(MissingExponentDigits)
+		}
+	} else if prefix == 'x' && typ == gotoken.FLOAT {
+		fail(MissingHexExponent)
+	}
+
+	if s.Peek() == 'i' {
+		typ = gotoken.IMAG
+		v = append(v, s.Next())
+	}
+
+	// Out of the base digits only make integers invalid: 09.5 and 09i are
+	// decimal
+	if typ == gotoken.INT && invalid {
+		if prefix == 'b' {
+			fail(InvalidBinaryDigit)
+		} else {
+			fail(InvalidOctalLiteral)
+		}
+	}
+	if !separatesDigits(v) {
+		fail(InvalidDigitSeparator)
+	}
+	if kind != "" {
+		return illegal(kind, v)
+	}
+	return Token{Type: typ, Value: string(v)}
+}
+
+// separatesDigits reports whether every digit separator of the number
+// literal v is between two digits, or a base prefix and a digit.
+func separatesDigits(v []rune) bool {
+	hex := false
+	prev := '.' // '0' for a digit, '_' for a separator, '.' for anything else
+	i := 0
+	if len(v) >= 2 && v[0] == '0' {
+		switch lower(v[1]) {
+		case 'x':
+			hex = true
+			fallthrough
+		case 'o', 'b':
+			prev = '0'
+			i = 2
+		}
+	}
+	for _, r := range v[i:] {
+		switch {
+		case isDigitSeparator(r):
+			if prev != '0' {
+				return false
+			}
+			prev = '_'
+		case isDecimalDigit(r) || hex && isHexDigit(r):
+			prev = '0'
+		default:
+			if prev == '_' {
+				return false
+			}
+			prev = '.'
+		}
+	}
+	return prev != '_'
+}
+
+// scanRune scans a rune literal, decoding its value.
+func scanRune(s *scanner.Scanner) Token {
+	v := []rune{s.Next()}
+	n := 0 // number of characters in the literal
+	var value rune
+	var kind ErrorKind // first invalid escape sequence
+	for {
+		r := s.Peek()
+		if isNewline(r) || r == scanner.EOF {
+			if kind != "" {
+				return illegal(kind, v)
+			}
+			return illegal(UnterminatedRune, v)
+		}
+		v = append(v, s.Next())
+		if r == '\'' {
+			break
+		}
+		n++
+		value = r
+		if r == '\\' {
+			c, k := scanEscape(s, &v, '\'')
+			value = c
+			if kind == "" {
+				kind = k
+			}
+		}
+	}
+	switch {
+	case kind != "":
+		return illegal(kind, v)
+	case n == 0:
+		return illegal(EmptyRune, v)
+	case n > 1:
+		return illegal(MultiCharRune, v)
+	}
+	return Token{Type: gotoken.CHAR, Value: string(v), char: value}
+}
+
+// scanString scans an interpreted string literal, decoding its value.
+func scanString(s *scanner.Scanner) Token {
+	v := []rune{s.Next()}
+	var value strings.Builder
+	var kind ErrorKind // first invalid escape sequence
+	for {
+		r := s.Peek()
+		if isNewline(r) || r == scanner.EOF {
+			if kind != "" {
+				return illegal(kind, v)
+			}
+			return illegal(UnterminatedString, v)
+		}
+		v = append(v, s.Next())
+		switch r {
+		case '"':
+			if kind != "" {
+				return illegal(kind, v)
+			}
+			return Token{Type: gotoken.STRING, Value: string(v), str: value.String()}
+		case '\\':
+			// Octal and \x escapes stand for a byte, the others for a
+			// character
+			byteEscape := isOctalDigit(s.Peek()) || s.Peek() == 'x'
+			c, k := scanEscape(s, &v, '"')
+			if kind == "" {
+				kind = k
+			}
+			if byteEscape {
+				value.WriteByte(byte(c))
+			} else {
+				value.WriteRune(c)
+			}
+		default:
+			value.WriteRune(r)
+		}
+	}
+}
+
+// scanRawString scans a raw string literal, which has no escape sequences,
+// discarding its carriage returns like go/scanner.
+func scanRawString(s *scanner.Scanner) Token {
+	v := []rune{s.Next()}
+	for s.Peek() != scanner.EOF {
+		cur := s.Next()
+		if cur == '\r' {
+			continue
+		}
+		v = append(v, cur)
+		if cur == '`' {
+			return Token{Type: gotoken.STRING, Value: string(v), str: string(v[1 : len(v)-1])}
+		}
+	}
+	return illegal(UnterminatedRawString, v)
+}
+
+// escapes are the values of the single character escape sequences, quotes
+// aside.
+var escapes = map[rune]rune{
+	'a':  '\a',
+	'b':  '\b',
+	'f':  '\f',
+	'n':  '\n',
+	'r':  '\r',
+	't':  '\t',
+	'v':  '\v',
+	'\\': '\\',
+}
+
+// scanEscape scans the escape sequence following a backslash in a literal
+// delimited by quote, appending it to v, and returns its value. The kind of
+// error is empty if the sequence is valid, or stops at the end of the line.
+func scanEscape(s *scanner.Scanner, v *[]rune, quote rune) (rune, ErrorKind) {
+	r := s.Peek()
+	if c, ok := escapes[r]; ok {
+		*v = append(*v, s.Next())
+		return c, ""
+	}
+	var digits, base int
+	var max rune
+	switch {
+	case r == quote:
+		*v = append(*v, s.Next())
+		return r, ""
+	case isOctalDigit(r):
+		digits, base, max = 3, 8, 255
+	case r == 'x':
+		*v = append(*v, s.Next())
+		digits, base, max = 2, 16, 255
+	case r == 'u':
+		*v = append(*v, s.Next())
+		digits, base, max = 4, 16, unicode.MaxRune
+	case r == 'U':
+		*v = append(*v, s.Next())
+		digits, base, max = 8, 16, unicode.MaxRune
+	case isNewline(r) || r == scanner.EOF:
+		return 0, ""
+	default:
+		*v = append(*v, s.Next())
+		return 0, UnknownEscape
+	}
+
+	var x rune
+	for i := 0; i < digits; i++ {
+		c := s.Peek()
+		if isNewline(c) || c == scanner.EOF {
+			return 0, ""
+		}
+		d := digitValue(c)
+		if d >= base {
+			// The delimiter ends the literal, other characters are part of
+			// it
+			if c != quote {
+				*v = append(*v, s.Next())
+			}
+			return 0, InvalidEscape
+		}
+		x = x*rune(base) + rune(d)
+		*v = append(*v, s.Next())
+	}
+	if x > max || x >= 0xD800 && x < 0xE000 {
+		return 0, InvalidCodePoint
+	}
+	return x, ""
+}
+
+// digitValue returns the value of the hexadecimal digit r, 16 if r is not
+// one.
+func digitValue(r rune) int {
+	switch {
+	case isDecimalDigit(r):
+		return int(r - '0')
+	case r >= 'a' && r <= 'f':
+		return int(r - 'a' + 10)
+	case r >= 'A' && r <= 'F':
+		return int(r - 'A' + 10)
+	}
+	return 16
+}
+
+// lower returns the lower case of the ASCII letter r.
+func lower(r rune) rune {
+	return ('a' - 'A') | r
+}
+
+func illegal(kind ErrorKind, v []rune) Token {
+	return Token{Type: gotoken.ILLEGAL, Value: string(v), illegal: kind}
 }
 
 func isIdent(s string) bool {
@@ -587,7 +1004,7 @@ func isNewline(r rune) bool {
 }
 
 func isWhitespace(r rune) bool {
-	return r == ' ' || r == '\t'
+	return r == ' ' || r == '\t' || r == '\r'
 }
 
 func isDigitSeparator(r rune) bool {
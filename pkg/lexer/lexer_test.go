@@ -0,0 +1,141 @@
+package lexer_test
+
+import (
+	gotoken "go/token"
+	"strings"
+	"testing"
+
+	"holang/pkg/diagnostics"
+	"holang/pkg/lexer"
+)
+
+func TestRuneLiterals(t *testing.T) {
+	tests := []struct {
+		src  string
+		want rune
+		code string // code of the error, if any
+	}{
+		{`'a'`, 'a', ""},
+		{`'é'`, 'é', ""},
+		{`'\a'`, '\a', ""},
+		{`'\v'`, '\v', ""},
+		{`'\\'`, '\\', ""},
+		{`'\''`, '\'', ""},
+		{`'\000'`, 0, ""},
+		{`'\101'`, 'A', ""},
+		{`'\377'`, 0xff, ""},
+		{`'\x41'`, 'A', ""},
+		{`'\xFf'`, 0xff, ""},
+		{`'\u00e9'`, 'é', ""},
+		{`'\U0001F600'`, '😀', ""},
+		{`'\U0010FFFF'`, 0x10ffff, ""},
+		{`''`, 0, lexer.EmptyRune.Code()},
+		{`'ab'`, 0, lexer.MultiCharRune.Code()},
+		{`'\x41x'`, 0, lexer.MultiCharRune.Code()},
+		{`'a`, 0, lexer.UnterminatedRune.Code()},
+		{`'\`, 0, lexer.UnterminatedRune.Code()},
+		{`'\x4`, 0, lexer.UnterminatedRune.Code()},
+		{`'\q'`, 0, lexer.UnknownEscape.Code()},
+		{`'\"'`, 0, lexer.UnknownEscape.Code()},
+		{`'\x4g'`, 0, lexer.InvalidEscape.Code()},
+		{`'\x4'`, 0, lexer.InvalidEscape.Code()},
+		{`'\12'`, 0, lexer.InvalidEscape.Code()},
+		{`'\u12'`, 0, lexer.InvalidEscape.Code()},
+		{`'\400'`, 0, lexer.InvalidCodePoint.Code()},
+		{`'\uD800'`, 0, lexer.InvalidCodePoint.Code()},
+		{`'\uDFFF'`, 0, lexer.InvalidCodePoint.Code()},
+		{`'\U00110000'`, 0, lexer.InvalidCodePoint.Code()},
+	}
+	for _, test := range tests {
+		tokens, err := lexer.Tokenize(nil, strings.NewReader(test.src), "rune.ho")
+		if test.code != "" {
+			list, _ := err.(diagnostics.List)
+			if len(list) == 0 || list[0].Code != test.code {
+				t.Errorf("%s: got %v, want an error %s", test.src, err, test.code)
+			}
+			if len(tokens) == 0 || tokens[0].Value != test.src {
+				t.Errorf("%s: the illegal token is not the whole literal: %v", test.src, tokens)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.src, err)
+			continue
+		}
+		if tokens[0].Type != gotoken.CHAR || tokens[0].Value != test.src {
+			t.Errorf("%s: got %s %q, want CHAR %q", test.src, tokens[0].Type, tokens[0].Value, test.src)
+		}
+		if got := tokens[0].Rune(); got != test.want {
+			t.Errorf("%s: got value %U, want %U", test.src, got, test.want)
+		}
+	}
+}
+
+func TestStringLiterals(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+		code string // code of the error, if any
+	}{
+		{`""`, "", ""},
+		{`"abc"`, "abc", ""},
+		{`"héllo"`, "héllo", ""},
+		{`"\a\b\f\n\r\t\v\\\""`, "\a\b\f\n\r\t\v\\\"", ""},
+		{`"\101\x41\u0041\U00000041"`, "AAAA", ""},
+		{`"\377\xff"`, "\xff\xff", ""},
+		{`"\u00ff"`, "ÿ", ""},
+		{`"\U0001F600"`, "😀", ""},
+		{`"a'b"`, "a'b", ""},
+		{"\"a\rb\"", "a\rb", ""},
+		{"``", "", ""},
+		{"`C:\\`", `C:\`, ""},
+		{"`\\n\"`", `\n"`, ""},
+		{"`a\nb`", "a\nb", ""},
+		{"`a\r\nb\r`", "a\nb", ""},
+		{`"abc`, "", lexer.UnterminatedString.Code()},
+		{"\"abc\n\"", "", lexer.UnterminatedString.Code()},
+		{`"abc\"`, "", lexer.UnterminatedString.Code()},
+		{"`abc", "", lexer.UnterminatedRawString.Code()},
+		{`"\q"`, "", lexer.UnknownEscape.Code()},
+		{`"\'"`, "", lexer.UnknownEscape.Code()},
+		{`"\x4g"`, "", lexer.InvalidEscape.Code()},
+		{`"\x4"`, "", lexer.InvalidEscape.Code()},
+		{`"\12"`, "", lexer.InvalidEscape.Code()},
+		{`"\400"`, "", lexer.InvalidCodePoint.Code()},
+		{`"\uD800"`, "", lexer.InvalidCodePoint.Code()},
+		{`"\U00110000"`, "", lexer.InvalidCodePoint.Code()},
+	}
+	for _, test := range tests {
+		tokens, err := lexer.Tokenize(nil, strings.NewReader(test.src), "string.ho")
+		if test.code != "" {
+			list, _ := err.(diagnostics.List)
+			if len(list) == 0 || list[0].Code != test.code {
+				t.Errorf("%q: got %v, want an error %s", test.src, err, test.code)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.src, err)
+			continue
+		}
+		if tokens[0].Type != gotoken.STRING {
+			t.Errorf("%q: got %s %q, want STRING", test.src, tokens[0].Type, tokens[0].Value)
+		}
+		if got := tokens[0].Unquoted(); got != test.want {
+			t.Errorf("%q: got value %q, want %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestCarriageReturns(t *testing.T) {
+	src := "package p\r\n\r\nvar s = `a\r\nb` // s\r\n"
+	tokens, err := lexer.Tokenize(nil, strings.NewReader(src), "crlf.ho")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tok := range tokens {
+		if tok.Type == gotoken.STRING && tok.Value != "`a\nb`" {
+			t.Errorf("got %q, want the carriage return discarded", tok.Value)
+		}
+	}
+}
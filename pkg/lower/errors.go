@@ -0,0 +1,33 @@
+package lower
+
+import (
+	"fmt"
+	gotoken "go/token"
+	"text/scanner"
+
+	"holang/pkg/diagnostics"
+)
+
+// Diagnostic codes of the lowering passes
+const (
+	InvalidPowOperand  = "T0001"
+	NegativeExponent   = "T0002"
+	ConstantOverflow   = "T0003"
+	UnknownPowType     = "T0004"
+	NonExhaustiveMatch = "T0005"
+)
+
+func (l *lowerer) errorf(from, to gotoken.Pos, code string, format string, args ...interface{}) {
+	l.errs = append(l.errs, &diagnostics.Diagnostic{
+		Severity: diagnostics.Error,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+		Primary:  diagnostics.Span{Start: l.position(from), End: l.position(to)},
+	})
+}
+
+// position converts pos to the representation used by diagnostics.
+func (l *lowerer) position(pos gotoken.Pos) scanner.Position {
+	p := l.fset.Position(pos)
+	return scanner.Position{Filename: p.Filename, Offset: p.Offset, Line: p.Line, Column: p.Column}
+}
@@ -0,0 +1,245 @@
+// Package lower completes the lowering of the holang constructs whose Go
+// translation depends on type information the parser does not have.
+package lower
+
+import (
+	goast "go/ast"
+	gotoken "go/token"
+	"go/types"
+	"reflect"
+	"sort"
+
+	"holang/pkg/check"
+	"holang/pkg/diagnostics"
+	"holang/pkg/parser"
+	hotoken "holang/pkg/token"
+)
+
+// Package lowers the match expressions and the exponentiations of files,
+// the files of a package, holang and Go ones alike, whose holang constructs
+// are given by constructs, and checks the matches over enums are
+// exhaustive. It returns the file declaring the helper functions the
+// lowered code calls, nil if it calls none, along with a diagnostics.List
+// of the constructs it could not lower or rejects.
+//
+// Lowering a construct needs the types of its operands, which may contain
+// other constructs to lower first, or be declared in other files: the
+// package is type checked again until every construct is lowered,
+// innermost ones first. The packages the files import are imported with
+// importer, a new check.Importer if nil.
+func Package(fset *gotoken.FileSet, files []*goast.File, constructs parser.Constructs, importer types.Importer) (*goast.File, error) {
+	if importer == nil {
+		importer = check.NewImporter()
+	}
+	l := &lowerer{
+		fset:     fset,
+		files:    files,
+		importer: importer,
+		pending:  map[goast.Node]bool{},
+		fileOf:   map[goast.Node]*goast.File{},
+	}
+	funcs := map[*goast.FuncLit]*parser.Match{}
+	for _, m := range constructs.Matches {
+		if m.Func != nil {
+			funcs[m.Func] = m
+			l.pending[m.Func] = true
+		}
+	}
+	for _, file := range files {
+		l.powAssignments(file)
+		goast.Inspect(file, func(node goast.Node) bool {
+			if e, ok := node.(*goast.BinaryExpr); ok && e.Op == hotoken.POW {
+				l.pending[e] = true
+			}
+			if l.pending[node] {
+				l.fileOf[node] = file
+			}
+			return true
+		})
+	}
+
+	for len(l.pending) > 0 {
+		info := l.check()
+		unknown := l.unknownTypes(info)
+		ready := []goast.Node{}
+		for node := range l.pending {
+			if !l.dependsOn(node) && !uses(info, node, unknown) {
+				ready = append(ready, node)
+			}
+		}
+		if len(ready) == 0 {
+			break
+		}
+		// Lower in source order, for the helpers and imports added to be
+		// deterministic
+		sort.Slice(ready, func(i, j int) bool { return ready[i].Pos() < ready[j].Pos() })
+		replacements := map[goast.Expr]goast.Expr{}
+		for _, node := range ready {
+			delete(l.pending, node)
+			switch node := node.(type) {
+			case *goast.FuncLit:
+				typeMatch(info, l.fileOf[node], funcs[node])
+			case *goast.BinaryExpr:
+				if x := l.lowerPow(info, l.fileOf[node], node); x != nil {
+					replacements[node] = x
+				}
+			}
+		}
+		for _, file := range files {
+			replace(file, replacements)
+		}
+	}
+	// Left pending, the powers whose operands depend on their results; the
+	// matches keep returning interface{}
+	for node := range l.pending {
+		if e, ok := node.(*goast.BinaryExpr); ok {
+			l.errorf(e.OpPos, e.OpPos+gotoken.Pos(len("**")), UnknownPowType, "cannot infer the type of the operands of **, which depend on its result")
+		}
+	}
+	if len(constructs.Matches) > 0 {
+		l.checkMatches(l.check(), constructs.Enums, constructs.Matches)
+	}
+	l.errs.Sort()
+	return l.helpers, l.errs.Err()
+}
+
+type lowerer struct {
+	fset     *gotoken.FileSet
+	files    []*goast.File
+	importer types.Importer
+	pending  map[goast.Node]bool        // constructs left to lower
+	fileOf   map[goast.Node]*goast.File // files of the constructs
+	helpers  *goast.File                // file of the helper functions, once one is needed
+	errs     diagnostics.List
+}
+
+// check type checks the package, ignoring errors: the information gathered
+// is partial as long as constructs are left to lower.
+func (l *lowerer) check() *types.Info {
+	_, info, _ := check.Files(l.fset, l.files[0].Name.Name, l.allFiles(), l.importer)
+	return info
+}
+
+// allFiles returns the files of the package, helpers included.
+func (l *lowerer) allFiles() []*goast.File {
+	if l.helpers == nil {
+		return l.files
+	}
+	return append(l.files[:len(l.files):len(l.files)], l.helpers)
+}
+
+// useHelper declares the helper function name, built by decl, in the
+// helpers of the package, unless the package already declares it.
+func (l *lowerer) useHelper(name string, decl func() *goast.FuncDecl) {
+	for _, file := range l.allFiles() {
+		for _, d := range file.Decls {
+			if f, ok := d.(*goast.FuncDecl); ok && f.Recv == nil && f.Name.Name == name {
+				return
+			}
+		}
+	}
+	if l.helpers == nil {
+		l.helpers = &goast.File{Name: goast.NewIdent(l.files[0].Name.Name)}
+	}
+	l.helpers.Decls = append(l.helpers.Decls, decl())
+}
+
+// dependsOn reports whether node contains another construct still pending.
+func (l *lowerer) dependsOn(node goast.Node) bool {
+	found := false
+	goast.Inspect(node, func(n goast.Node) bool {
+		found = found || (n != node && l.pending[n])
+		return !found
+	})
+	return found
+}
+
+// unknownTypes returns the variables whose type is not known yet: those
+// initialized by an expression holding a construct still pending, or
+// another such variable.
+func (l *lowerer) unknownTypes(info *types.Info) map[types.Object]bool {
+	unknown := map[types.Object]bool{}
+	pending := func(exprs ...goast.Expr) bool {
+		for _, x := range exprs {
+			if x != nil && (l.dependsOn(x) || l.pending[x] || uses(info, x, unknown)) {
+				return true
+			}
+		}
+		return false
+	}
+	define := func(exprs ...goast.Expr) {
+		for _, x := range exprs {
+			if id, ok := x.(*goast.Ident); ok && info.Defs[id] != nil {
+				unknown[info.Defs[id]] = true
+			}
+		}
+	}
+	// A single pass suffices: variables are used after their declaration,
+	// but for package-level ones
+	inspect := func(node goast.Node) bool {
+		switch node := node.(type) {
+		case *goast.AssignStmt:
+			if node.Tok == gotoken.DEFINE && pending(node.Rhs...) {
+				define(node.Lhs...)
+			}
+		case *goast.RangeStmt:
+			if node.Tok == gotoken.DEFINE && pending(node.X) {
+				define(node.Key, node.Value)
+			}
+		case *goast.ValueSpec:
+			if node.Type == nil && pending(node.Values...) {
+				for _, name := range node.Names {
+					define(name)
+				}
+			}
+		}
+		return true
+	}
+	for _, file := range l.files {
+		goast.Inspect(file, inspect)
+	}
+	return unknown
+}
+
+// uses reports whether node refers to one of objects.
+func uses(info *types.Info, node goast.Node, objects map[types.Object]bool) bool {
+	found := false
+	goast.Inspect(node, func(n goast.Node) bool {
+		if id, ok := n.(*goast.Ident); ok && objects[info.Uses[id]] {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// replace substitutes the expressions of root found in replacements.
+func replace(root goast.Node, replacements map[goast.Expr]goast.Expr) {
+	if len(replacements) == 0 {
+		return
+	}
+	exprType := reflect.TypeOf((*goast.Expr)(nil)).Elem()
+	substitute := func(v reflect.Value) {
+		if x, ok := v.Interface().(goast.Expr); ok && replacements[x] != nil {
+			v.Set(reflect.ValueOf(replacements[x]))
+		}
+	}
+	goast.Inspect(root, func(node goast.Node) bool {
+		v := reflect.ValueOf(node)
+		if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+			return true
+		}
+		v = v.Elem()
+		for i := 0; i < v.NumField(); i++ {
+			switch f := v.Field(i); {
+			case f.Type() == exprType && !f.IsNil():
+				substitute(f)
+			case f.Kind() == reflect.Slice && f.Type().Elem() == exprType:
+				for j := 0; j < f.Len(); j++ {
+					substitute(f.Index(j))
+				}
+			}
+		}
+		return true
+	})
+}
@@ -0,0 +1,155 @@
+package lower_test
+
+import (
+	goast "go/ast"
+	gotoken "go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"holang/pkg/check"
+	"holang/pkg/diagnostics"
+	"holang/pkg/lexer"
+	"holang/pkg/lower"
+	"holang/pkg/parser"
+)
+
+// lowerFile lowers the package of the file of source src, and returns the
+// file along with the codes of the errors found, nil if there are any. The
+// lowered package must type check.
+func lowerFile(t *testing.T, src string) (*goast.File, []string) {
+	t.Helper()
+	fset := gotoken.NewFileSet()
+	p := parser.NewParser(lexer.NewLexer(fset, strings.NewReader(src), "p.ho"))
+	file, err := p.ParseFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	importer := check.NewImporter()
+	helpers, err := lower.Package(fset, []*goast.File{&file}, p.Constructs(), importer)
+	codes := []string{}
+	if list, ok := err.(diagnostics.List); ok {
+		for _, d := range list {
+			codes = append(codes, d.Code)
+		}
+		return nil, codes
+	}
+	files := []*goast.File{&file}
+	if helpers != nil {
+		files = append(files, helpers)
+	}
+	if _, _, diags := check.Files(fset, "p", files, importer); diags.Err() != nil {
+		t.Fatal(diags.Err())
+	}
+	return &file, codes
+}
+
+// lowerValue lowers a package declaring decls and a variable v initialized
+// with value, and returns the lowered value along with the codes of the
+// errors found.
+func lowerValue(t *testing.T, decls, value string) (string, []string) {
+	t.Helper()
+	file, codes := lowerFile(t, "package p\n\n"+decls+"\n\nvar v = "+value+"\n")
+	if file == nil {
+		return "", codes
+	}
+	spec := file.Decls[len(file.Decls)-1].(*goast.GenDecl).Specs[0].(*goast.ValueSpec)
+	return types.ExprString(spec.Values[0]), codes
+}
+
+func TestPow(t *testing.T) {
+	tests := []struct {
+		name  string
+		decls string
+		value string
+		want  string
+	}{
+		{"constant", "", "2 ** 10", "1024"},
+		{"float constant", "", "2 ** 0.5", "1.4142135623730951"},
+		{"decimal constant", "", "1.1 ** 2", "1.21"},
+		{"tiny constant", "", "1e-200 ** 2", "1.0 / 1" + strings.Repeat("0", 400) + ".0"},
+		{"typed constant", "const c int8 = 2", "c ** 6", "int8(64)"},
+		{"integer", "var i int", "i ** 3", "int(holangPowUint(uint64(i), 3))"},
+		{"signed exponent", "var i, n int", "i ** n", "int(holangPow(int64(i), int64(n)))"},
+		{"unsigned exponent", "var i int; var u uint64", "i ** u", "int(holangPowUint(uint64(i), u))"},
+		{"negative base", "var u uint8", "(-2) ** u", "int(holangPowUint(18446744073709551614, uint64(u)))"},
+		{"float", "var f float64", "f ** 2", "math.Pow(f, 2)"},
+		{"float32", "var f float32", "f ** 0.5", "float32(math.Pow(float64(f), float64(0.5)))"},
+		{"untyped base", "var f float32", "2 ** f", "float32(math.Pow(float64(2), float64(f)))"},
+		{"complex", "var c complex128; var f float64", "c ** f", "cmplx.Pow(c, complex(f, 0))"},
+		{"nested", "var i int", "i ** i ** 2", "int(holangPow(int64(i), int64(int(holangPowUint(uint64(i), 2)))))"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, codes := lowerValue(t, test.decls, test.value)
+			if len(codes) > 0 {
+				t.Fatalf("got errors %v", codes)
+			}
+			if got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPowErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		decls string
+		value string
+		want  string
+	}{
+		{"negative exponent", "var i int", "i ** -1", lower.NegativeExponent},
+		{"fractional exponent", "var i int", "i ** 0.5", lower.InvalidPowOperand},
+		{"non-numeric base", "var s string", "s ** 2", lower.InvalidPowOperand},
+		{"overflow", "", "2 ** 1000", lower.ConstantOverflow},
+		{"float overflow", "", "1e300 ** 2", lower.ConstantOverflow},
+		{"depending on its result", "var a = v ** 2", "a", lower.UnknownPowType},
+		{"float32 overflow", "const c float32 = 1e20", "c ** 2", lower.ConstantOverflow},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, codes := lowerValue(t, test.decls, test.value)
+			if len(codes) != 1 || codes[0] != test.want {
+				t.Errorf("got errors %v, want %s", codes, test.want)
+			}
+		})
+	}
+}
+
+func TestMatchType(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"typed and untyped arms", "var z int8 = match c { Red => 1, Green => y, _ => 0 }", "int8"},
+		{"typed arm last", "var z = match c { Red => 1, _ => y }", "int8"},
+		{"constants", "var z = match c { Red => 1, _ => 2.5 }", "float64"},
+		{"variable type", "var z int8 = match c { Red => 1, _ => 2 }", "int8"},
+		{"assignment", "var z uint16\n\nfunc f() { z = match c { Red => 1, _ => 2 } }", "uint16"},
+		{"result", "func f() uint { return match c { Red => 1, _ => 2 } }", "uint"},
+		{"argument", "func f(float32) {}\n\nfunc g() { f(match c { Red => 1, _ => 2 }) }", "float32"},
+		{"interface", "var err error\n\nvar z = match c { Red => err, _ => nil }", "error"},
+		{"not representable", "var z = match c { Red => 300, _ => y }", "interface{}"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			src := "package p\n\nenum Color { Red, Green, Blue }\n\nvar c Color\n\nvar y int8\n\n" + test.src + "\n"
+			file, codes := lowerFile(t, src)
+			if len(codes) > 0 {
+				t.Fatalf("got errors %v", codes)
+			}
+			got := ""
+			goast.Inspect(file, func(node goast.Node) bool {
+				if f, ok := node.(*goast.FuncLit); ok && got == "" {
+					got = types.ExprString(f.Type.Results.List[0].Type)
+				}
+				return true
+			})
+			if got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
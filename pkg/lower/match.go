@@ -0,0 +1,368 @@
+package lower
+
+import (
+	goast "go/ast"
+	"go/constant"
+	goparser "go/parser"
+	gotoken "go/token"
+	"go/types"
+	"strings"
+
+	"holang/pkg/check"
+	"holang/pkg/diagnostics"
+	"holang/pkg/parser"
+)
+
+// typeMatch replaces the placeholder result type of the function literal
+// wrapping m with the type of its arms. A match whose arms have no type in
+// common, or a type that cannot be named from the file, keeps returning
+// interface{}.
+func typeMatch(info *types.Info, file *goast.File, m *parser.Match) {
+	if typ := armsType(info, m, contextType(info, file, m)); typ != nil {
+		if expr := typeExpr(typ, file, info); expr != nil {
+			m.Func.Type.Results.List[0].Type = expr
+		}
+	}
+}
+
+// armsType returns the type the values of the arms of m can all be given,
+// nil if there is none. It is the type of one of the arms, untyped
+// constants taking the type of the typed arms or else their default type,
+// unless it cannot be assigned to context, the type the match is assigned
+// to, if known.
+func armsType(info *types.Info, m *parser.Match, context types.Type) types.Type {
+	values := []types.TypeAndValue{}
+	for _, stmt := range m.Switch.Body.List {
+		ret := stmt.(*goast.CaseClause).Body[0].(*goast.ReturnStmt)
+		tv, ok := info.Types[ret.Results[0]]
+		if !ok || tv.Type == nil || tv.IsVoid() || tv.Type == types.Typ[types.Invalid] {
+			return nil
+		}
+		if typ := untypedType(info, ret.Results[0]); typ != nil {
+			// Recorded with the type it was converted to, interface{}
+			// or its default type
+			tv.Type = typ
+		}
+		values = append(values, tv)
+	}
+	typed, untyped := []types.Type{}, []types.Type{}
+	for _, tv := range values {
+		if !isUntyped(tv.Type) {
+			typed = append(typed, tv.Type)
+		} else if t := types.Default(tv.Type); !isUntyped(t) {
+			untyped = append(untyped, t)
+		}
+	}
+	for _, typ := range append(typed, untyped...) {
+		if fits(values, typ) && (context == nil || types.AssignableTo(typ, context)) {
+			return typ
+		}
+	}
+	if context != nil && fits(values, context) {
+		return context
+	}
+	return nil
+}
+
+// untypedType returns the type of x if it is untyped, nil otherwise.
+func untypedType(info *types.Info, x goast.Expr) types.Type {
+	switch x := x.(type) {
+	case *goast.BasicLit:
+		return types.Typ[map[gotoken.Token]types.BasicKind{
+			gotoken.INT:    types.UntypedInt,
+			gotoken.FLOAT:  types.UntypedFloat,
+			gotoken.IMAG:   types.UntypedComplex,
+			gotoken.CHAR:   types.UntypedRune,
+			gotoken.STRING: types.UntypedString,
+		}[x.Kind]]
+	case *goast.Ident:
+		switch obj := info.Uses[x].(type) {
+		case *types.Const:
+			if isUntyped(obj.Type()) {
+				return obj.Type()
+			}
+		case *types.Nil:
+			return types.Typ[types.UntypedNil]
+		}
+	case *goast.ParenExpr:
+		return untypedType(info, x.X)
+	case *goast.UnaryExpr:
+		return untypedType(info, x.X)
+	case *goast.BinaryExpr:
+		switch x.Op {
+		case gotoken.EQL, gotoken.NEQ, gotoken.LSS, gotoken.LEQ, gotoken.GTR, gotoken.GEQ:
+			return types.Typ[types.UntypedBool]
+		case gotoken.SHL, gotoken.SHR:
+			return untypedType(info, x.X)
+		}
+		// The kinds of untyped numbers are ordered
+		a, b := untypedType(info, x.X), untypedType(info, x.Y)
+		if a == nil || b == nil {
+			return nil
+		}
+		if b.(*types.Basic).Kind() > a.(*types.Basic).Kind() {
+			return b
+		}
+		return a
+	}
+	return nil
+}
+
+// fits reports whether values can all be given type typ: the typed ones
+// are assignable to it, the constants are representable by it.
+func fits(values []types.TypeAndValue, typ types.Type) bool {
+	for _, tv := range values {
+		if !types.AssignableTo(tv.Type, typ) {
+			return false
+		}
+		if b, ok := typ.Underlying().(*types.Basic); ok && tv.Value != nil && isUntyped(tv.Type) && !representableBy(tv.Value, b) {
+			return false
+		}
+	}
+	return true
+}
+
+// representableBy reports whether the constant v is representable by a
+// value of the basic type typ, int, uint and uintptr being 64-bit.
+func representableBy(v constant.Value, typ *types.Basic) bool {
+	info := typ.Info()
+	switch {
+	case info&types.IsInteger != 0:
+		v = constant.ToInt(v)
+		if v.Kind() != constant.Int {
+			return false
+		}
+		bits := 64
+		switch typ.Kind() {
+		case types.Int8, types.Uint8:
+			bits = 8
+		case types.Int16, types.Uint16:
+			bits = 16
+		case types.Int32, types.Uint32:
+			bits = 32
+		}
+		if info&types.IsUnsigned != 0 {
+			return constant.Sign(v) >= 0 && constant.BitLen(v) <= bits
+		}
+		// -1<<(bits-1) <= v < 1<<(bits-1)
+		if constant.Sign(v) < 0 {
+			v = constant.BinaryOp(constant.UnaryOp(gotoken.SUB, v, 0), gotoken.SUB, constant.MakeInt64(1))
+		}
+		return constant.BitLen(v) < bits
+	case info&(types.IsFloat|types.IsComplex) != 0:
+		if info&types.IsFloat != 0 && constant.ToFloat(v).Kind() != constant.Float {
+			return false
+		}
+		return constant.ToComplex(v).Kind() == constant.Complex && representable(typ, v)
+	}
+	return true
+}
+
+// contextType returns the type the value of m, a match expression of file,
+// is assigned to: by a variable declaration or an assignment, returned as
+// the result of a function or passed as an argument. It returns nil if it
+// is not known.
+func contextType(info *types.Info, file *goast.File, m *parser.Match) types.Type {
+	path := []goast.Node{} // from file to m.Func
+	found := false
+	goast.Inspect(file, func(node goast.Node) bool {
+		switch {
+		case found:
+		case node == nil:
+			path = path[:len(path)-1]
+		default:
+			path = append(path, node)
+			found = node == m.Func
+			return !found
+		}
+		return false
+	})
+	if !found || len(path) < 2 {
+		return nil
+	}
+	// The call of the function literal, out of parentheses
+	var value goast.Expr = path[len(path)-2].(*goast.CallExpr)
+	i := len(path) - 3
+	for ; i >= 0; i-- {
+		paren, ok := path[i].(*goast.ParenExpr)
+		if !ok {
+			break
+		}
+		value = paren
+	}
+	if i < 0 {
+		return nil
+	}
+	switch parent := path[i].(type) {
+	case *goast.ValueSpec:
+		if parent.Type != nil {
+			return info.TypeOf(parent.Type)
+		}
+	case *goast.AssignStmt:
+		if parent.Tok == gotoken.ASSIGN && len(parent.Lhs) == len(parent.Rhs) {
+			for j, x := range parent.Rhs {
+				if x == value {
+					return info.TypeOf(parent.Lhs[j])
+				}
+			}
+		}
+	case *goast.ReturnStmt:
+		results := resultTypes(info, path[:i])
+		if results != nil && results.Len() == len(parent.Results) {
+			for j, x := range parent.Results {
+				if x == value {
+					return results.At(j).Type()
+				}
+			}
+		}
+	case *goast.CallExpr:
+		sig, ok := info.TypeOf(parent.Fun).(*types.Signature)
+		if !ok || parent.Ellipsis.IsValid() {
+			return nil
+		}
+		for j, x := range parent.Args {
+			switch {
+			case x != value:
+			case sig.Variadic() && j >= sig.Params().Len()-1:
+				return sig.Params().At(sig.Params().Len() - 1).Type().(*types.Slice).Elem()
+			case j < sig.Params().Len():
+				return sig.Params().At(j).Type()
+			}
+		}
+	}
+	return nil
+}
+
+// resultTypes returns the results of the innermost function of path.
+func resultTypes(info *types.Info, path []goast.Node) *types.Tuple {
+	for i := len(path) - 1; i >= 0; i-- {
+		var typ types.Type
+		switch f := path[i].(type) {
+		case *goast.FuncDecl:
+			if obj := info.Defs[f.Name]; obj != nil {
+				typ = obj.Type()
+			}
+		case *goast.FuncLit:
+			typ = info.TypeOf(f)
+		default:
+			continue
+		}
+		if sig, ok := typ.(*types.Signature); ok {
+			return sig.Results()
+		}
+		return nil
+	}
+	return nil
+}
+
+// typeExpr returns the expression denoting typ in file, nil if it refers to
+// a package the file does not import.
+func typeExpr(typ types.Type, file *goast.File, info *types.Info) goast.Expr {
+	names := map[string]string{}
+	for _, spec := range file.Imports {
+		// Dot and unnamed imports are implicit definitions
+		obj := info.Implicits[spec]
+		if obj == nil && spec.Name != nil {
+			obj = info.Defs[spec.Name]
+		}
+		if pkgName, ok := obj.(*types.PkgName); ok {
+			names[pkgName.Imported().Path()] = pkgName.Name()
+		}
+	}
+	nameable := true
+	s := types.TypeString(typ, func(pkg *types.Package) string {
+		if pkg.Name() == file.Name.Name && pkg.Path() == file.Name.Name {
+			return ""
+		}
+		if name, ok := names[pkg.Path()]; ok && name != "_" {
+			return strings.TrimPrefix(name, ".")
+		}
+		nameable = false
+		return pkg.Name()
+	})
+	if !nameable || strings.Contains(s, "invalid type") {
+		return nil
+	}
+	expr, err := goparser.ParseExpr(s)
+	if err != nil {
+		return nil
+	}
+	return expr
+}
+
+// checkMatches records an error for every match over a value of an enum,
+// one of enums or of the holang packages imported, that does not cover all
+// the variants and lacks a `_` arm.
+func (l *lowerer) checkMatches(info *types.Info, enums []*parser.Enum, matches []*parser.Match) {
+	byType := map[types.Object]*parser.Enum{}
+	for _, enum := range enums {
+		if obj := info.Defs[enum.Name]; obj != nil {
+			byType[obj] = enum
+		}
+	}
+	for _, m := range matches {
+		if m.Default() != nil || m.Switch.Tag == nil {
+			continue
+		}
+		named, ok := info.TypeOf(m.Switch.Tag).(*types.Named)
+		if !ok || named.Obj().Pkg() == nil {
+			continue
+		}
+		enum := byType[named.Obj()]
+		if enum == nil {
+			enum = l.importedEnum(named.Obj())
+		}
+		if enum == nil {
+			continue
+		}
+		covered := map[string]bool{}
+		for _, stmt := range m.Switch.Body.List {
+			for _, pattern := range stmt.(*goast.CaseClause).List {
+				if tv := info.Types[pattern]; tv.Value != nil {
+					covered[tv.Value.ExactString()] = true
+				}
+			}
+		}
+		missing := []string{}
+		scope := named.Obj().Pkg().Scope()
+		for _, variant := range enum.Variants {
+			c, ok := scope.Lookup(variant.Name).(*types.Const)
+			if ok && !covered[c.Val().ExactString()] {
+				missing = append(missing, variant.Name)
+			}
+		}
+		if len(missing) > 0 {
+			l.nonExhaustiveMatch(m, enum, missing)
+		}
+	}
+}
+
+// importedEnum returns the enum declaring the type obj in a holang package
+// imported, nil if there is none.
+func (l *lowerer) importedEnum(obj *types.TypeName) *parser.Enum {
+	imp, ok := l.importer.(*check.Importer)
+	if !ok {
+		return nil
+	}
+	for _, enum := range imp.Enums(obj.Pkg().Path()) {
+		if enum.Name.Name == obj.Name() {
+			return enum
+		}
+	}
+	return nil
+}
+
+func (l *lowerer) nonExhaustiveMatch(m *parser.Match, enum *parser.Enum, missing []string) {
+	keyword := m.Switch.Switch
+	l.errs = append(l.errs, &diagnostics.Diagnostic{
+		Severity: diagnostics.Error,
+		Code:     NonExhaustiveMatch,
+		Message:  "non-exhaustive match over enum " + enum.Name.Name,
+		Primary:  diagnostics.Span{Start: l.position(keyword), End: l.position(keyword + gotoken.Pos(len("match")))},
+		Notes:    []string{"missing variants: " + strings.Join(missing, ", ")},
+		Fixes: []diagnostics.Fix{{
+			Message:     "cover the remaining variants with a default arm",
+			Replacement: "_ => ...",
+		}},
+	})
+}
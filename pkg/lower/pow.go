@@ -0,0 +1,423 @@
+package lower
+
+import (
+	goast "go/ast"
+	"go/constant"
+	gotoken "go/token"
+	"go/types"
+	"math"
+	"math/cmplx"
+	"strconv"
+	"strings"
+
+	"holang/pkg/astutil"
+	hotoken "holang/pkg/token"
+)
+
+// Names of the functions computing integer powers, to signed and unsigned
+// exponents, declared in the helpers of the packages that need them.
+const (
+	powHelper     = "holangPow"
+	powUintHelper = "holangPowUint"
+)
+
+// powAssignments turns every `x **= y` of file into `x = x ** y`.
+func (l *lowerer) powAssignments(file *goast.File) {
+	goast.Inspect(file, func(node goast.Node) bool {
+		assign, ok := node.(*goast.AssignStmt)
+		if !ok || assign.Tok != hotoken.POW_ASSIGN {
+			return true
+		}
+		if len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+			l.errorf(assign.Pos(), assign.End(), InvalidPowOperand, "assignment operation **= requires single-valued expressions")
+			return true
+		}
+		assign.Rhs[0] = &goast.BinaryExpr{X: assign.Lhs[0], OpPos: assign.TokPos, Op: hotoken.POW, Y: assign.Rhs[0]}
+		assign.Tok = gotoken.ASSIGN
+		return true
+	})
+}
+
+// lowerPow returns the Go expression computing e, a `x ** y` expression of
+// file, or nil if it cannot be lowered.
+//
+// Like a shift, the power has the type of its base, an untyped base taking
+// the type of a floating-point or complex exponent. Integer powers are
+// computed exactly, with the wrap-around of integer multiplication, and
+// require an integer exponent. Powers of constants are constant.
+func (l *lowerer) lowerPow(info *types.Info, file *goast.File, e *goast.BinaryExpr) goast.Expr {
+	x, y := info.Types[e.X], info.Types[e.Y]
+	if !valid(x) || !valid(y) {
+		l.errorf(e.Pos(), e.End(), UnknownPowType, "cannot infer the type of the operands of **")
+		return nil
+	}
+	typ := powType(x.Type, y.Type)
+	basic, ok := typ.Underlying().(*types.Basic)
+	if !ok || basic.Info()&types.IsNumeric == 0 {
+		l.errorf(e.X.Pos(), e.X.End(), InvalidPowOperand, "operator ** not defined on %s", x.Type)
+		return nil
+	}
+	if exp, ok := y.Type.Underlying().(*types.Basic); !ok || exp.Info()&types.IsNumeric == 0 {
+		l.errorf(e.Y.Pos(), e.Y.End(), InvalidPowOperand, "operator ** not defined on %s", y.Type)
+		return nil
+	}
+	isInteger := basic.Info()&types.IsInteger != 0
+	if isInteger && !isIntegral(y) {
+		l.errorf(e.Y.Pos(), e.Y.End(), InvalidPowOperand, "the exponent of a power of %s must be an integer, found %s", typ, y.Type)
+		return nil
+	}
+	if isInteger && y.Value != nil && constant.Sign(y.Value) < 0 {
+		l.errorf(e.Y.Pos(), e.Y.End(), NegativeExponent, "negative exponent %s for a power of %s", y.Value, typ)
+		return nil
+	}
+
+	if x.Value != nil && y.Value != nil {
+		v := foldPow(basic, x.Value, y.Value)
+		if v == nil || !representable(basic, v) {
+			l.errorf(e.OpPos, e.OpPos+gotoken.Pos(len("**")), ConstantOverflow, "constant overflow in %s ** %s", x.Value, y.Value)
+			return nil
+		}
+		return l.convert(info, file, e, typ, nil, constExpr(v))
+	}
+
+	switch {
+	case isInteger:
+		// Integers of any size are multiplied as 64-bit ones, the bits of
+		// the result being the same. Exponents that cannot be negative are
+		// left unsigned, for the largest ones not to be taken for negative
+		// ones.
+		helper, argType := powHelper, types.Typ[types.Int64]
+		if yb, ok := y.Type.Underlying().(*types.Basic); y.Value != nil || (ok && yb.Info()&types.IsUnsigned != 0) {
+			helper, argType = powUintHelper, types.Typ[types.Uint64]
+		}
+		l.useHelper(helper, func() *goast.FuncDecl { return powFunc(helper, argType.Name()) })
+		return l.convert(info, file, e, typ, argType, &goast.CallExpr{
+			Fun: goast.NewIdent(helper),
+			Args: []goast.Expr{
+				l.helperArg(info, file, e, argType, x, e.X),
+				l.helperArg(info, file, e, argType, y, e.Y),
+			},
+		})
+	case basic.Info()&types.IsFloat != 0:
+		float64Type := types.Typ[types.Float64]
+		return l.convert(info, file, e, typ, float64Type, &goast.CallExpr{
+			Fun: &goast.SelectorExpr{X: l.useImport(file, "math"), Sel: goast.NewIdent("Pow")},
+			Args: []goast.Expr{
+				l.convert(info, file, e, float64Type, x.Type, e.X),
+				l.convert(info, file, e, float64Type, y.Type, e.Y),
+			},
+		})
+	}
+	complex128Type := types.Typ[types.Complex128]
+	exp := e.Y
+	if b := y.Type.Underlying().(*types.Basic); b.Info()&(types.IsComplex|types.IsUntyped) == 0 {
+		// Real numbers cannot be converted to complex ones
+		exp = &goast.CallExpr{
+			Fun: goast.NewIdent("complex"),
+			Args: []goast.Expr{
+				l.convert(info, file, e, types.Typ[types.Float64], y.Type, e.Y),
+				&goast.BasicLit{Kind: gotoken.INT, Value: "0"},
+			},
+		}
+	}
+	return l.convert(info, file, e, typ, complex128Type, &goast.CallExpr{
+		Fun: &goast.SelectorExpr{X: l.useImport(file, "math/cmplx"), Sel: goast.NewIdent("Pow")},
+		Args: []goast.Expr{
+			l.convert(info, file, e, complex128Type, x.Type, e.X),
+			exp,
+		},
+	})
+}
+
+func valid(tv types.TypeAndValue) bool {
+	return tv.Type != nil && tv.Type != types.Typ[types.Invalid] && !tv.IsVoid()
+}
+
+// powType returns the type of the power of a base of type x to an exponent
+// of type y.
+func powType(x, y types.Type) types.Type {
+	if !isUntyped(x) {
+		return x
+	}
+	if isUntyped(y) {
+		// Both are constants: the kinds of untyped numbers are ordered
+		if y.(*types.Basic).Kind() > x.(*types.Basic).Kind() {
+			return y
+		}
+		return x
+	}
+	if b, ok := y.Underlying().(*types.Basic); ok && b.Info()&(types.IsFloat|types.IsComplex) != 0 {
+		return y
+	}
+	return types.Default(x)
+}
+
+func isUntyped(typ types.Type) bool {
+	b, ok := typ.(*types.Basic)
+	return ok && b.Info()&types.IsUntyped != 0
+}
+
+// isIntegral reports whether tv is of an integer type or is a constant
+// representable by an integer.
+func isIntegral(tv types.TypeAndValue) bool {
+	if tv.Value != nil && isUntyped(tv.Type) {
+		return constant.ToInt(tv.Value).Kind() == constant.Int
+	}
+	b, ok := tv.Type.Underlying().(*types.Basic)
+	return ok && b.Info()&types.IsInteger != 0
+}
+
+// foldPow returns the value of x ** y as a constant of the kind of typ, nil
+// if it overflows. Integer exponents give exact results, computed by
+// repeated squaring.
+func foldPow(typ *types.Basic, x, y constant.Value) constant.Value {
+	switch {
+	case typ.Info()&types.IsInteger != 0:
+		x = constant.ToInt(x)
+	case typ.Info()&types.IsFloat != 0:
+		x = constant.ToFloat(x)
+	default:
+		x = constant.ToComplex(x)
+	}
+	n := constant.ToInt(y)
+	if n.Kind() != constant.Int || (x.Kind() != constant.Int && !smallExponent(n)) {
+		return inexactPow(x, y)
+	}
+	negative := constant.Sign(n) < 0
+	if negative {
+		n = constant.UnaryOp(gotoken.SUB, n, 0)
+	}
+	one := constant.MakeInt64(1)
+	z := constant.ToComplex(one)
+	if x.Kind() != constant.Complex {
+		z = one
+	}
+	for ; constant.Sign(n) > 0; n = constant.Shift(n, gotoken.SHR, 1) {
+		if constant.Compare(constant.BinaryOp(n, gotoken.AND, one), gotoken.EQL, one) {
+			z = constant.BinaryOp(z, gotoken.MUL, x)
+		}
+		x = constant.BinaryOp(x, gotoken.MUL, x)
+		// Untyped constants are only required to hold 512 bits
+		if x.Kind() == constant.Int && (constant.BitLen(z) > 512 || constant.BitLen(x) > 1024) {
+			return nil
+		}
+	}
+	if negative {
+		if constant.Sign(z) == 0 {
+			return nil
+		}
+		z = constant.BinaryOp(one, gotoken.QUO, z)
+	}
+	return z
+}
+
+// smallExponent reports whether n is small enough for powers of fractional
+// constants to be computed exactly.
+func smallExponent(n constant.Value) bool {
+	limit := constant.MakeInt64(64)
+	return constant.Compare(n, gotoken.LEQ, limit) && constant.Compare(n, gotoken.GEQ, constant.UnaryOp(gotoken.SUB, limit, 0))
+}
+
+// inexactPow returns the value of x ** y, computed with 64-bit floating-point
+// numbers, nil if it is not finite.
+func inexactPow(x, y constant.Value) constant.Value {
+	if x.Kind() != constant.Complex && y.Kind() != constant.Complex {
+		base, _ := constant.Float64Val(constant.ToFloat(x))
+		exp, _ := constant.Float64Val(constant.ToFloat(y))
+		z := math.Pow(base, exp)
+		if math.IsInf(z, 0) || math.IsNaN(z) {
+			return nil
+		}
+		return constant.MakeFloat64(z)
+	}
+	z := cmplx.Pow(complex128Val(x), complex128Val(y))
+	if cmplx.IsInf(z) || cmplx.IsNaN(z) {
+		return nil
+	}
+	return constant.BinaryOp(
+		constant.MakeFloat64(real(z)),
+		gotoken.ADD,
+		constant.MakeImag(constant.MakeFloat64(imag(z))),
+	)
+}
+
+func complex128Val(v constant.Value) complex128 {
+	v = constant.ToComplex(v)
+	re, _ := constant.Float64Val(constant.Real(v))
+	im, _ := constant.Float64Val(constant.Imag(v))
+	return complex(re, im)
+}
+
+// representable reports whether v, a power of type typ, can be given by a
+// literal: if typ is a floating-point or complex type, by finite values of
+// its size, float64 if it is untyped, which are ratios of integers.
+func representable(typ *types.Basic, v constant.Value) bool {
+	if typ.Info()&(types.IsFloat|types.IsComplex) == 0 {
+		return true
+	}
+	for _, part := range []constant.Value{constant.Real(v), constant.Imag(v)} {
+		f, _ := constant.Float64Val(part)
+		if typ.Kind() == types.Float32 || typ.Kind() == types.Complex64 {
+			f32, _ := constant.Float32Val(part)
+			f = float64(f32)
+		}
+		if math.IsInf(f, 0) || constant.Num(part).Kind() != constant.Int || constant.Denom(part).Kind() != constant.Int {
+			return false
+		}
+	}
+	return true
+}
+
+// constExpr returns the literal expression of v, exact for floating-point
+// values float64 does not hold.
+func constExpr(v constant.Value) goast.Expr {
+	switch v.Kind() {
+	case constant.Int:
+		if constant.Sign(v) < 0 {
+			return &goast.UnaryExpr{Op: gotoken.SUB, X: constExpr(constant.UnaryOp(gotoken.SUB, v, 0))}
+		}
+		return &goast.BasicLit{Kind: gotoken.INT, Value: v.ExactString()}
+	case constant.Float:
+		if constant.Sign(v) < 0 {
+			return &goast.UnaryExpr{Op: gotoken.SUB, X: constExpr(constant.UnaryOp(gotoken.SUB, v, 0))}
+		}
+		// Values held by float64 are given by their shortest literal
+		f, exact := constant.Float64Val(v)
+		s := floatLit(strconv.FormatFloat(f, 'g', -1, 64))
+		if !math.IsInf(f, 0) && (exact || constant.Compare(constant.MakeFromLiteral(s, gotoken.FLOAT, 0), gotoken.EQL, v)) {
+			return &goast.BasicLit{Kind: gotoken.FLOAT, Value: s}
+		}
+		num := &goast.BasicLit{Kind: gotoken.FLOAT, Value: floatLit(constant.Num(v).ExactString())}
+		if den := constant.Denom(v); constant.Compare(den, gotoken.NEQ, constant.MakeInt64(1)) {
+			return &goast.BinaryExpr{X: num, Op: gotoken.QUO, Y: &goast.BasicLit{Kind: gotoken.FLOAT, Value: floatLit(den.ExactString())}}
+		}
+		return num
+	}
+	return &goast.CallExpr{
+		Fun:  goast.NewIdent("complex"),
+		Args: []goast.Expr{constExpr(constant.ToFloat(constant.Real(v))), constExpr(constant.ToFloat(constant.Imag(v)))},
+	}
+}
+
+// floatLit returns the number s as a floating-point literal.
+func floatLit(s string) string {
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+// convert returns x, an operand of e in file, of type from, converted to
+// type to. Untyped operands and operands already of type to are left as is.
+func (l *lowerer) convert(info *types.Info, file *goast.File, e *goast.BinaryExpr, to, from types.Type, x goast.Expr) goast.Expr {
+	if isUntyped(to) || (from != nil && (isUntyped(from) || types.Identical(from, to))) {
+		return x
+	}
+	typ := typeExpr(to, file, info)
+	if typ == nil {
+		l.errorf(e.Pos(), e.End(), UnknownPowType, "the type %s of %s ** %s cannot be named in this file", to, types.ExprString(e.X), types.ExprString(e.Y))
+		return x
+	}
+	return &goast.CallExpr{Fun: typ, Args: []goast.Expr{x}}
+}
+
+// powFunc returns the declaration of the helper name computing powers of
+// integers of type typ, int64 or uint64:
+//
+//	func holangPow(x, y int64) int64 {
+//		if y < 0 {
+//			panic("negative exponent")
+//		}
+//		z := int64(1)
+//		for ; y > 0; y >>= 1 {
+//			if y&1 == 1 {
+//				z *= x
+//			}
+//			x *= x
+//		}
+//		return z
+//	}
+//
+// Unsigned exponents are never negative, the helper for uint64 does not
+// check them.
+func powFunc(name, typ string) *goast.FuncDecl {
+	x, y, z := goast.NewIdent("x"), goast.NewIdent("y"), goast.NewIdent("z")
+	intType := goast.NewIdent(typ)
+	lit := func(value string) *goast.BasicLit {
+		return &goast.BasicLit{Kind: gotoken.INT, Value: value}
+	}
+	body := []goast.Stmt{}
+	if typ == "int64" {
+		body = append(body, &goast.IfStmt{
+			Cond: &goast.BinaryExpr{X: y, Op: gotoken.LSS, Y: lit("0")},
+			Body: &goast.BlockStmt{List: []goast.Stmt{&goast.ExprStmt{X: &goast.CallExpr{
+				Fun:  goast.NewIdent("panic"),
+				Args: []goast.Expr{&goast.BasicLit{Kind: gotoken.STRING, Value: `"negative exponent"`}},
+			}}}},
+		})
+	}
+	return &goast.FuncDecl{
+		Name: goast.NewIdent(name),
+		Type: &goast.FuncType{
+			Params: &goast.FieldList{List: []*goast.Field{
+				{Names: []*goast.Ident{x, y}, Type: intType},
+			}},
+			Results: &goast.FieldList{List: []*goast.Field{{Type: intType}}},
+		},
+		Body: &goast.BlockStmt{List: append(body,
+			&goast.AssignStmt{
+				Lhs: []goast.Expr{z},
+				Tok: gotoken.DEFINE,
+				Rhs: []goast.Expr{&goast.CallExpr{Fun: intType, Args: []goast.Expr{lit("1")}}},
+			},
+			&goast.ForStmt{
+				Cond: &goast.BinaryExpr{X: y, Op: gotoken.GTR, Y: lit("0")},
+				Post: &goast.AssignStmt{Lhs: []goast.Expr{y}, Tok: gotoken.SHR_ASSIGN, Rhs: []goast.Expr{lit("1")}},
+				Body: &goast.BlockStmt{List: []goast.Stmt{
+					&goast.IfStmt{
+						Cond: &goast.BinaryExpr{
+							X:  &goast.BinaryExpr{X: y, Op: gotoken.AND, Y: lit("1")},
+							Op: gotoken.EQL,
+							Y:  lit("1"),
+						},
+						Body: &goast.BlockStmt{List: []goast.Stmt{
+							&goast.AssignStmt{Lhs: []goast.Expr{z}, Tok: gotoken.MUL_ASSIGN, Rhs: []goast.Expr{x}},
+						}},
+					},
+					&goast.AssignStmt{Lhs: []goast.Expr{x}, Tok: gotoken.MUL_ASSIGN, Rhs: []goast.Expr{x}},
+				}},
+			},
+			&goast.ReturnStmt{Results: []goast.Expr{z}},
+		)},
+	}
+}
+
+// helperArg returns x, an operand of e in file, converted to to, int64 or
+// uint64, as the argument of a power helper. Constants are given by their
+// 64 bits, for which a conversion of negative or too large values would be
+// rejected.
+func (l *lowerer) helperArg(info *types.Info, file *goast.File, e *goast.BinaryExpr, to types.Type, x types.TypeAndValue, expr goast.Expr) goast.Expr {
+	if x.Value == nil {
+		return l.convert(info, file, e, to, x.Type, expr)
+	}
+	v := constant.ToInt(x.Value)
+	if constant.BitLen(v) > 64 {
+		l.errorf(expr.Pos(), expr.End(), ConstantOverflow, "constant %s overflows %s", x.Value, to)
+		return expr
+	}
+	v = constant.BinaryOp(v, gotoken.AND, constant.MakeUint64(math.MaxUint64))
+	if to == types.Typ[types.Int64] && constant.BitLen(v) == 64 {
+		v = constant.BinaryOp(v, gotoken.SUB, constant.Shift(constant.MakeInt64(1), gotoken.SHL, 64))
+	}
+	return constExpr(v)
+}
+
+// useImport returns the name under which the package at path can be
+// referred to in file, importing it if file does not already.
+func (l *lowerer) useImport(file *goast.File, path string) *goast.Ident {
+	name, spec := astutil.Import(file.Imports, path)
+	if spec != nil {
+		astutil.AddImport(file, spec)
+	}
+	return name
+}
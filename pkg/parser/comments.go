@@ -0,0 +1,112 @@
+package parser
+
+import (
+	goast "go/ast"
+	gotoken "go/token"
+
+	"holang/pkg/lexer"
+)
+
+// commentGroup is a goast.CommentGroup along with the tokens around it, to
+// tell which node it documents.
+type commentGroup struct {
+	*goast.CommentGroup
+	first, last lexer.Token  // first and last comments of the group
+	prev        *lexer.Token // token before the group, nil at the start of the file
+	next        *lexer.Token // token after the group, nil until read
+	trailing    bool         // whether the group starts on the line of prev
+}
+
+// addComment records a comment read from the lexer. Like go/parser, it
+// groups the comments separated by at most one newline, but a comment
+// following a token on its line only groups with comments on its last line.
+func (p *Parser) addComment(token lexer.Token) {
+	comment := &goast.Comment{Slash: token.Pos, Text: token.Value}
+	if n := len(p.comments); n > 0 {
+		last := p.comments[n-1]
+		maxLine := last.last.End.Line + 1
+		if last.trailing {
+			maxLine = last.last.End.Line
+		}
+		if last.next == nil && token.Position.Line <= maxLine {
+			last.List = append(last.List, comment)
+			last.last = token
+			return
+		}
+	}
+	p.comments = append(p.comments, &commentGroup{
+		CommentGroup: &goast.CommentGroup{List: []*goast.Comment{comment}},
+		first:        token,
+		last:         token,
+		prev:         p.prev,
+		trailing:     p.prev != nil && p.prev.End.Line == token.Position.Line,
+	})
+}
+
+// addToken records a token read from the lexer, closing the last comment
+// group.
+func (p *Parser) addToken(token lexer.Token) {
+	if token.Type == gotoken.SEMICOLON && token.Value == "\n" {
+		// Automatic semicolons stand for the newline after the previous token
+		return
+	}
+	if n := len(p.comments); n > 0 && p.comments[n-1].next == nil {
+		p.comments[n-1].next = &token
+	}
+	p.prev = &token
+}
+
+// leadComment returns the comment group documenting the node starting with
+// token: the group right before token, ending on the previous line.
+func (p *Parser) leadComment(token lexer.Token) *goast.CommentGroup {
+	for i := len(p.comments) - 1; i >= 0; i-- {
+		group := p.comments[i]
+		if group.first.Position.Offset > token.Position.Offset {
+			continue
+		}
+		if group.next != nil && group.next.Position.Offset == token.Position.Offset && !group.trailing &&
+			group.last.End.Line+1 == token.Position.Line {
+			return group.CommentGroup
+		}
+		return nil
+	}
+	return nil
+}
+
+// lineComment returns the comment group following the last token consumed,
+// on its line.
+func (p *Parser) lineComment() *goast.CommentGroup {
+	// The comment comes after the automatic semicolon ending the line
+	p.peek(1)
+	for i := len(p.comments) - 1; i >= 0; i-- {
+		group := p.comments[i]
+		if group.first.Position.Offset < p.last.End.Offset {
+			break
+		}
+		if group.trailing && group.prev.Position.Offset == p.last.Position.Offset {
+			return group.CommentGroup
+		}
+	}
+	return nil
+}
+
+// fileComments returns every comment group of the file.
+func (p *Parser) fileComments() []*goast.CommentGroup {
+	groups := []*goast.CommentGroup{}
+	for _, group := range p.comments {
+		groups = append(groups, group.CommentGroup)
+	}
+	return groups
+}
+
+// setSpecComments attaches doc and comment to spec.
+func setSpecComments(spec goast.Spec, doc, comment *goast.CommentGroup) {
+	switch spec := spec.(type) {
+	case *goast.ImportSpec:
+		spec.Doc, spec.Comment = doc, comment
+	case *goast.ValueSpec:
+		spec.Doc, spec.Comment = doc, comment
+	case *goast.TypeSpec:
+		spec.Doc, spec.Comment = doc, comment
+	}
+}
@@ -0,0 +1,230 @@
+package parser
+
+import (
+	goast "go/ast"
+	gotoken "go/token"
+)
+
+// https://golang.org/ref/spec#TopLevelDecl
+func (p *Parser) eatTopLevelDecl() (goast.Decl, error) {
+	token, err := p.currentToken()
+	if err != nil {
+		return nil, err
+	}
+	switch token.Type {
+	case gotoken.CONST, gotoken.TYPE, gotoken.VAR:
+		return p.eatDeclaration()
+	case gotoken.FUNC:
+		return p.eatFuncDecl()
+	}
+	return nil, p.errorf(token, UnexpectedToken, "expected declaration, found %s", describeToken(token))
+}
+
+// https://golang.org/ref/spec#Function_declarations
+// https://golang.org/ref/spec#Method_declarations
+func (p *Parser) eatFuncDecl() (*goast.FuncDecl, error) {
+	keyword, err := p.eat(gotoken.FUNC)
+	if err != nil {
+		return nil, err
+	}
+	decl := &goast.FuncDecl{Doc: p.leadComment(keyword)}
+	if _, err := p.try(gotoken.LPAREN); err == nil {
+		if decl.Recv, err = p.eatParameters(); err != nil {
+			return nil, err
+		}
+	}
+	if decl.Name, err = p.eatIdent(); err != nil {
+		return nil, err
+	}
+	if decl.Type, err = p.eatSignature(); err != nil {
+		return nil, err
+	}
+	decl.Type.Func = keyword.Pos
+	if _, err := p.try(gotoken.LBRACE); err == nil {
+		if decl.Body, err = p.eatBlockStmt(); err != nil {
+			return nil, err
+		}
+	}
+	return decl, nil
+}
+
+// https://golang.org/ref/spec#Declaration
+func (p *Parser) eatDeclaration() (*goast.GenDecl, error) {
+	token, err := p.currentToken()
+	if err != nil {
+		return nil, err
+	}
+	switch token.Type {
+	case gotoken.CONST:
+		return p.eatGenDecl(gotoken.CONST, p.eatConstSpec)
+	case gotoken.TYPE:
+		return p.eatGenDecl(gotoken.TYPE, p.eatTypeSpec)
+	case gotoken.VAR:
+		return p.eatGenDecl(gotoken.VAR, p.eatVarSpec)
+	}
+	return nil, p.errorf(token, UnexpectedToken, "expected declaration, found %s", describeToken(token))
+}
+
+// eatGenDecl parses the declarations sharing the `keyword ( Spec ; ... )`
+// shape, calling eatSpec with the index of each spec within the group.
+func (p *Parser) eatGenDecl(keyword gotoken.Token, eatSpec func(index int) (goast.Spec, error)) (*goast.GenDecl, error) {
+	decl := &goast.GenDecl{Tok: keyword}
+	token, err := p.eat(keyword)
+	if err != nil {
+		return decl, err
+	}
+	decl.TokPos = token.Pos
+	decl.Doc = p.leadComment(token)
+	if lparen, err := p.eat(gotoken.LPAREN); err == nil {
+		decl.Lparen = lparen.Pos
+		for {
+			if rparen, err := p.eat(gotoken.RPAREN); err == nil {
+				decl.Rparen = rparen.Pos
+				return decl, nil
+			}
+			first, err := p.currentToken()
+			if err != nil {
+				return decl, err
+			}
+			spec, err := eatSpec(len(decl.Specs))
+			if err != nil {
+				return decl, err
+			}
+			setSpecComments(spec, p.leadComment(first), p.lineComment())
+			decl.Specs = append(decl.Specs, spec)
+			// The semicolon may be omitted before the closing parenthesis
+			if _, err := p.try(gotoken.RPAREN); err == nil {
+				continue
+			}
+			if _, err = p.eat(gotoken.SEMICOLON); err != nil {
+				return decl, err
+			}
+		}
+	}
+	spec, err := eatSpec(0)
+	if err != nil {
+		return decl, err
+	}
+	setSpecComments(spec, nil, p.lineComment())
+	decl.Specs = append(decl.Specs, spec)
+	return decl, nil
+}
+
+// https://golang.org/ref/spec#Constant_declarations
+//
+// Within a parenthesized const declaration list the expression list may be
+// omitted from any but the first ConstSpec, in which case the previous list
+// is implicitly repeated: like go/parser, the ValueSpec is then left without
+// Values.
+func (p *Parser) eatConstSpec(index int) (goast.Spec, error) {
+	spec := &goast.ValueSpec{}
+	names, err := p.eatIdentifierList()
+	if err != nil {
+		return nil, err
+	}
+	spec.Names = names
+	if p.atSpecEnd() {
+		if index == 0 {
+			token, _ := p.currentToken()
+			return nil, p.errorf(token, MissingConstValue, "missing init expression for const declaration")
+		}
+		return spec, nil
+	}
+	if _, err := p.try(gotoken.ASSIGN); err != nil {
+		typ, err := p.eatType()
+		if err != nil {
+			return nil, err
+		}
+		spec.Type = typ
+	}
+	if _, err := p.eat(gotoken.ASSIGN); err != nil {
+		return nil, err
+	}
+	values, err := p.eatExpressionList()
+	if err != nil {
+		return nil, err
+	}
+	spec.Values = values
+	return spec, nil
+}
+
+// https://golang.org/ref/spec#Variable_declarations
+func (p *Parser) eatVarSpec(int) (goast.Spec, error) {
+	spec := &goast.ValueSpec{}
+	names, err := p.eatIdentifierList()
+	if err != nil {
+		return nil, err
+	}
+	spec.Names = names
+	if _, err := p.try(gotoken.ASSIGN); err != nil {
+		typ, err := p.eatType()
+		if err != nil {
+			return nil, err
+		}
+		spec.Type = typ
+		if p.atSpecEnd() {
+			return spec, nil
+		}
+	}
+	if _, err := p.eat(gotoken.ASSIGN); err != nil {
+		return nil, err
+	}
+	values, err := p.eatExpressionList()
+	if err != nil {
+		return nil, err
+	}
+	spec.Values = values
+	return spec, nil
+}
+
+// https://golang.org/ref/spec#Type_declarations
+func (p *Parser) eatTypeSpec(int) (goast.Spec, error) {
+	spec := &goast.TypeSpec{}
+	name, err := p.eatIdent()
+	if err != nil {
+		return nil, err
+	}
+	spec.Name = name
+	// https://golang.org/ref/spec#Alias_declarations
+	if assign, err := p.eat(gotoken.ASSIGN); err == nil {
+		spec.Assign = assign.Pos
+	}
+	typ, err := p.eatType()
+	if err != nil {
+		return nil, err
+	}
+	spec.Type = typ
+	return spec, nil
+}
+
+func (p *Parser) atSpecEnd() bool {
+	token, err := p.currentToken()
+	return err != nil || token.Type == gotoken.SEMICOLON || token.Type == gotoken.RPAREN
+}
+
+// https://golang.org/ref/spec#IdentifierList
+func (p *Parser) eatIdentifierList() ([]*goast.Ident, error) {
+	ident, err := p.eatIdent()
+	if err != nil {
+		return nil, err
+	}
+	idents := []*goast.Ident{ident}
+	for {
+		if _, err := p.eat(gotoken.COMMA); err != nil {
+			return idents, nil
+		}
+		ident, err := p.eatIdent()
+		if err != nil {
+			return nil, err
+		}
+		idents = append(idents, ident)
+	}
+}
+
+func (p *Parser) eatIdent() (*goast.Ident, error) {
+	ident, err := p.eat(gotoken.IDENT)
+	if err != nil {
+		return nil, err
+	}
+	return &goast.Ident{NamePos: ident.Pos, Name: ident.Value}, nil
+}
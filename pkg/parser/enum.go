@@ -0,0 +1,264 @@
+package parser
+
+import (
+	goast "go/ast"
+	gotoken "go/token"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+
+	hotoken "holang/pkg/token"
+)
+
+// Enum is an enum declaration, recorded so that later passes can tell which
+// named types are enums and which constants are their variants.
+type Enum struct {
+	Name     *goast.Ident
+	Variants []*goast.Ident
+}
+
+// eatEnumDecl parses `enum Name { Variant; ... }`, variants being separated
+// by semicolons (or newlines) or by commas, and lowers it to the Go
+// declarations of a typed iota constant block:
+//
+//	type Name int
+//
+//	const (
+//		Variant Name = iota
+//		...
+//	)
+//
+//	func (e Name) String() string
+//	func NameValues() []Name
+//	func ParseName(s string) (Name, error)
+func (p *Parser) eatEnumDecl() ([]goast.Decl, error) {
+	keyword, err := p.eat(hotoken.ENUM)
+	if err != nil {
+		return nil, err
+	}
+	name, err := p.eatIdent()
+	if err != nil {
+		return nil, err
+	}
+	lbrace, err := p.eat(gotoken.LBRACE)
+	if err != nil {
+		return nil, err
+	}
+	enum := &Enum{Name: name, Variants: []*goast.Ident{}}
+	docs, comments := []*goast.CommentGroup{}, []*goast.CommentGroup{}
+	seen := map[string]bool{}
+	for {
+		if _, err := p.try(gotoken.RBRACE); err == nil {
+			break
+		}
+		token, err := p.currentToken()
+		if err != nil {
+			return nil, err
+		}
+		variant, err := p.eatIdent()
+		if err != nil {
+			return nil, err
+		}
+		if seen[variant.Name] {
+			return nil, p.errorf(token, DuplicateVariant, "%s redeclared in enum %s", variant.Name, name.Name)
+		}
+		seen[variant.Name] = true
+		enum.Variants = append(enum.Variants, variant)
+		docs = append(docs, p.leadComment(token))
+		comments = append(comments, p.lineComment())
+		if _, err := p.try(gotoken.RBRACE); err == nil {
+			continue
+		}
+		if _, err := p.eat(gotoken.COMMA); err == nil {
+			continue
+		}
+		if _, err := p.eat(gotoken.SEMICOLON); err != nil {
+			return nil, err
+		}
+	}
+	rbrace, err := p.eat(gotoken.RBRACE)
+	if err != nil {
+		return nil, err
+	}
+	if len(enum.Variants) == 0 {
+		return nil, p.errorf(rbrace, EmptyEnum, "enum %s has no variants", name.Name)
+	}
+	p.enums = append(p.enums, enum)
+
+	typeDecl := &goast.GenDecl{
+		Doc:    p.leadComment(keyword),
+		TokPos: keyword.Pos,
+		Tok:    gotoken.TYPE,
+		Specs: []goast.Spec{&goast.TypeSpec{
+			Name: name,
+			Type: goast.NewIdent("int"),
+		}},
+	}
+
+	constDecl := &goast.GenDecl{
+		TokPos: keyword.Pos,
+		Tok:    gotoken.CONST,
+		Lparen: lbrace.Pos,
+		Rparen: rbrace.Pos,
+	}
+	for i, variant := range enum.Variants {
+		spec := &goast.ValueSpec{Doc: docs[i], Names: []*goast.Ident{variant}, Comment: comments[i]}
+		if i == 0 {
+			spec.Type = goast.NewIdent(name.Name)
+			spec.Values = []goast.Expr{goast.NewIdent("iota")}
+		}
+		constDecl.Specs = append(constDecl.Specs, spec)
+	}
+
+	return []goast.Decl{
+		typeDecl,
+		constDecl,
+		p.enumStringMethod(enum),
+		p.enumValuesFunc(enum),
+		p.enumParseFunc(enum),
+	}, nil
+}
+
+//	func (e Name) String() string {
+//		switch e {
+//		case Variant:
+//			return "Variant"
+//		...
+//		}
+//		return fmt.Sprintf("Name(%d)", int(e))
+//	}
+func (p *Parser) enumStringMethod(enum *Enum) *goast.FuncDecl {
+	fmt := p.useImport("fmt")
+	recv := enum.freshName("e")
+	body := &goast.BlockStmt{}
+	for _, variant := range enum.Variants {
+		body.List = append(body.List, &goast.CaseClause{
+			List: []goast.Expr{goast.NewIdent(variant.Name)},
+			Body: []goast.Stmt{&goast.ReturnStmt{Results: []goast.Expr{stringLit(variant.Name)}}},
+		})
+	}
+	return &goast.FuncDecl{
+		Recv: fields(field(recv, goast.NewIdent(enum.Name.Name))),
+		Name: goast.NewIdent("String"),
+		Type: &goast.FuncType{
+			Params:  fields(),
+			Results: fields(field("", goast.NewIdent("string"))),
+		},
+		Body: &goast.BlockStmt{List: []goast.Stmt{
+			&goast.SwitchStmt{Tag: goast.NewIdent(recv), Body: body},
+			&goast.ReturnStmt{Results: []goast.Expr{&goast.CallExpr{
+				Fun: &goast.SelectorExpr{X: fmt, Sel: goast.NewIdent("Sprintf")},
+				Args: []goast.Expr{
+					stringLit(enum.Name.Name + "(%d)"),
+					&goast.CallExpr{Fun: goast.NewIdent("int"), Args: []goast.Expr{goast.NewIdent(recv)}},
+				},
+			}}},
+		}},
+	}
+}
+
+//	func NameValues() []Name {
+//		return []Name{Variant, ...}
+//	}
+func (p *Parser) enumValuesFunc(enum *Enum) *goast.FuncDecl {
+	values := &goast.CompositeLit{Type: &goast.ArrayType{Elt: goast.NewIdent(enum.Name.Name)}}
+	for _, variant := range enum.Variants {
+		values.Elts = append(values.Elts, goast.NewIdent(variant.Name))
+	}
+	return &goast.FuncDecl{
+		Name: goast.NewIdent(enum.Name.Name + "Values"),
+		Type: &goast.FuncType{
+			Params:  fields(),
+			Results: fields(field("", &goast.ArrayType{Elt: goast.NewIdent(enum.Name.Name)})),
+		},
+		Body: &goast.BlockStmt{List: []goast.Stmt{
+			&goast.ReturnStmt{Results: []goast.Expr{values}},
+		}},
+	}
+}
+
+//	func ParseName(s string) (Name, error) {
+//		switch s {
+//		case "Variant":
+//			return Variant, nil
+//		...
+//		}
+//		return 0, fmt.Errorf("invalid Name: %q", s)
+//	}
+func (p *Parser) enumParseFunc(enum *Enum) *goast.FuncDecl {
+	fmt := p.useImport("fmt")
+	param := enum.freshName("s")
+	body := &goast.BlockStmt{}
+	for _, variant := range enum.Variants {
+		body.List = append(body.List, &goast.CaseClause{
+			List: []goast.Expr{stringLit(variant.Name)},
+			Body: []goast.Stmt{&goast.ReturnStmt{Results: []goast.Expr{
+				goast.NewIdent(variant.Name),
+				goast.NewIdent("nil"),
+			}}},
+		})
+	}
+	name := "Parse" + enum.Name.Name
+	if !enum.Name.IsExported() {
+		name = "parse" + capitalize(enum.Name.Name)
+	}
+	return &goast.FuncDecl{
+		Name: goast.NewIdent(name),
+		Type: &goast.FuncType{
+			Params: fields(field(param, goast.NewIdent("string"))),
+			Results: fields(
+				field("", goast.NewIdent(enum.Name.Name)),
+				field("", goast.NewIdent("error")),
+			),
+		},
+		Body: &goast.BlockStmt{List: []goast.Stmt{
+			&goast.SwitchStmt{Tag: goast.NewIdent(param), Body: body},
+			&goast.ReturnStmt{Results: []goast.Expr{
+				&goast.BasicLit{Kind: gotoken.INT, Value: "0"},
+				&goast.CallExpr{
+					Fun: &goast.SelectorExpr{X: fmt, Sel: goast.NewIdent("Errorf")},
+					Args: []goast.Expr{
+						stringLit("invalid " + enum.Name.Name + ": %q"),
+						goast.NewIdent(param),
+					},
+				},
+			}},
+		}},
+	}
+}
+
+// freshName returns name, suffixed with underscores until it does not
+// shadow the enum or one of its variants in the generated code.
+func (enum *Enum) freshName(name string) string {
+	for taken := true; taken; {
+		taken = name == enum.Name.Name
+		for _, variant := range enum.Variants {
+			taken = taken || name == variant.Name
+		}
+		if taken {
+			name += "_"
+		}
+	}
+	return name
+}
+
+func capitalize(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(r)) + name[size:]
+}
+
+func stringLit(s string) *goast.BasicLit {
+	return &goast.BasicLit{Kind: gotoken.STRING, Value: strconv.Quote(s)}
+}
+
+func fields(list ...*goast.Field) *goast.FieldList {
+	return &goast.FieldList{List: list}
+}
+
+func field(name string, typ goast.Expr) *goast.Field {
+	f := &goast.Field{Type: typ}
+	if name != "" {
+		f.Names = []*goast.Ident{goast.NewIdent(name)}
+	}
+	return f
+}
@@ -0,0 +1,53 @@
+package parser
+
+import (
+	"fmt"
+	gotoken "go/token"
+
+	"holang/pkg/diagnostics"
+	"holang/pkg/lexer"
+	"holang/pkg/token"
+)
+
+// Diagnostic codes of the parser
+const (
+	UnexpectedToken   = "P0001"
+	UnexpectedEOF     = "P0002"
+	BlankPackageName  = "P0003"
+	MissingConstValue = "P0004"
+	MissingSliceIndex = "P0005"
+	MissingCondition  = "P0006"
+	ExpectedCall      = "P0007"
+	EmptyEnum         = "P0008"
+	DuplicateVariant  = "P0009"
+	DuplicateDefault  = "P0011" // P0010 moved to lower.NonExhaustiveMatch
+	BlankPattern      = "P0012"
+)
+
+func (p *Parser) errorf(token lexer.Token, code string, format string, args ...interface{}) error {
+	return &diagnostics.Diagnostic{
+		Severity: diagnostics.Error,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+		Primary:  diagnostics.Span{Start: token.Position, End: token.End},
+	}
+}
+
+// describe returns how a kind of token is referred to in error messages.
+func describe(_type gotoken.Token) string {
+	if _type.IsLiteral() {
+		return _type.String()
+	}
+	return fmt.Sprintf("'%s'", token.String(_type))
+}
+
+// describeToken returns how a token is referred to in error messages.
+func describeToken(token lexer.Token) string {
+	switch {
+	case token.Type == gotoken.SEMICOLON && token.Value == "\n":
+		return "newline"
+	case token.Type.IsLiteral(), token.Type == gotoken.ILLEGAL:
+		return fmt.Sprintf("%s %s", token.Type, token.Value)
+	}
+	return describe(token.Type)
+}
@@ -0,0 +1,416 @@
+package parser
+
+import (
+	goast "go/ast"
+	gotoken "go/token"
+
+	hotoken "holang/pkg/token"
+)
+
+// https://golang.org/ref/spec#ExpressionList
+func (p *Parser) eatExpressionList() ([]goast.Expr, error) {
+	expr, err := p.eatExpression()
+	if err != nil {
+		return nil, err
+	}
+	exprs := []goast.Expr{expr}
+	for {
+		if _, err := p.eat(gotoken.COMMA); err != nil {
+			return exprs, nil
+		}
+		expr, err := p.eatExpression()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+}
+
+// https://golang.org/ref/spec#Expression
+//
+// Types are accepted wherever an expression is, as they may appear as
+// operands of conversions and of builtins like make or new; telling them
+// apart is left to the type checker.
+func (p *Parser) eatExpression() (goast.Expr, error) {
+	return p.eatBinaryExpr(gotoken.LowestPrec + 1)
+}
+
+// https://golang.org/ref/spec#Operator_precedence
+//
+// Binary operators are parsed by precedence climbing: operators binding at
+// least as tightly as prec1 are consumed, and their right operand is made
+// of the operators binding strictly more tightly, which makes them all left
+// associative.
+func (p *Parser) eatBinaryExpr(prec1 int) (goast.Expr, error) {
+	x, err := p.eatUnaryExpr()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, err := p.currentToken()
+		if err != nil {
+			return x, nil
+		}
+		prec := op.Type.Precedence()
+		if prec < prec1 {
+			return x, nil
+		}
+		p.next()
+		y, err := p.eatBinaryExpr(prec + 1)
+		if err != nil {
+			return nil, err
+		}
+		x = &goast.BinaryExpr{X: x, OpPos: op.Pos, Op: op.Type, Y: y}
+	}
+}
+
+// https://golang.org/ref/spec#UnaryExpr
+func (p *Parser) eatUnaryExpr() (goast.Expr, error) {
+	token, err := p.currentToken()
+	if err != nil {
+		return nil, err
+	}
+	switch token.Type {
+	case gotoken.ADD, gotoken.SUB, gotoken.NOT, gotoken.XOR, gotoken.AND:
+		p.next()
+		x, err := p.eatUnaryExpr()
+		if err != nil {
+			return nil, err
+		}
+		return &goast.UnaryExpr{OpPos: token.Pos, Op: token.Type, X: x}, nil
+	case gotoken.MUL:
+		p.next()
+		x, err := p.eatUnaryExpr()
+		if err != nil {
+			return nil, err
+		}
+		return &goast.StarExpr{Star: token.Pos, X: x}, nil
+	case hotoken.POW:
+		// Two indirections, as in **p or (**T)(x)
+		p.splitPow()
+		return p.eatUnaryExpr()
+	case gotoken.ARROW:
+		if next, _ := p.peek(1); next.Type == gotoken.CHAN {
+			typ, err := p.eatChanType()
+			if err != nil {
+				return nil, err
+			}
+			return p.eatPrimaryExprSuffixes(typ)
+		}
+		p.next()
+		x, err := p.eatUnaryExpr()
+		if err != nil {
+			return nil, err
+		}
+		return &goast.UnaryExpr{OpPos: token.Pos, Op: token.Type, X: x}, nil
+	}
+	return p.eatPowerExpr()
+}
+
+// The holang power operator binds more tightly than any other binary
+// operator and than the unary operators on its left, and is right
+// associative: -2 ** 3 ** 2 is -(2 ** (3 ** 2)). Its right operand may still
+// be a unary expression, as in 2 ** -1.
+func (p *Parser) eatPowerExpr() (goast.Expr, error) {
+	x, err := p.eatPrimaryExpr()
+	if err != nil {
+		return nil, err
+	}
+	pow, err := p.eat(hotoken.POW)
+	if err != nil {
+		return x, nil
+	}
+	y, err := p.eatUnaryExpr()
+	if err != nil {
+		return nil, err
+	}
+	return &goast.BinaryExpr{X: x, OpPos: pow.Pos, Op: pow.Type, Y: y}, nil
+}
+
+// https://golang.org/ref/spec#PrimaryExpr
+func (p *Parser) eatPrimaryExpr() (goast.Expr, error) {
+	x, err := p.eatOperand()
+	if err != nil {
+		return nil, err
+	}
+	return p.eatPrimaryExprSuffixes(x)
+}
+
+// eatPrimaryExprSuffixes parses the selectors, indexes, slices, type
+// assertions, calls and literal values applied to x.
+func (p *Parser) eatPrimaryExprSuffixes(x goast.Expr) (goast.Expr, error) {
+	for {
+		token, err := p.currentToken()
+		if err != nil {
+			return x, nil
+		}
+		switch token.Type {
+		case gotoken.PERIOD:
+			x, err = p.eatSelectorOrTypeAssertion(x)
+		case gotoken.LBRACK:
+			x, err = p.eatIndexOrSlice(x)
+		case gotoken.LPAREN:
+			x, err = p.eatArguments(x)
+		case gotoken.LBRACE:
+			if !isLiteralType(x) || (p.exprLev < 0 && isTypeName(x)) {
+				return x, nil
+			}
+			x, err = p.eatLiteralValue(x)
+		default:
+			return x, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// https://golang.org/ref/spec#Operand
+func (p *Parser) eatOperand() (goast.Expr, error) {
+	token, err := p.currentToken()
+	if err != nil {
+		return nil, err
+	}
+	switch token.Type {
+	case gotoken.INT, gotoken.FLOAT, gotoken.IMAG, gotoken.CHAR, gotoken.STRING:
+		p.next()
+		return &goast.BasicLit{ValuePos: token.Pos, Kind: token.Type, Value: token.Value}, nil
+	case gotoken.IDENT:
+		return p.eatIdent()
+	case gotoken.LPAREN:
+		p.next()
+		p.exprLev++
+		x, err := p.eatExpression()
+		p.exprLev--
+		if err != nil {
+			return nil, err
+		}
+		rparen, err := p.eat(gotoken.RPAREN)
+		if err != nil {
+			return nil, err
+		}
+		return &goast.ParenExpr{Lparen: token.Pos, X: x, Rparen: rparen.Pos}, nil
+	case gotoken.FUNC:
+		return p.eatFuncTypeOrLit()
+	case gotoken.LBRACK, gotoken.STRUCT, gotoken.MAP, gotoken.CHAN, gotoken.INTERFACE:
+		return p.eatType()
+	case hotoken.MATCH:
+		return p.eatMatchExpr()
+	}
+	// The token is left for the enclosing statement or declaration to
+	// resynchronize on
+	p.error(p.errorf(token, UnexpectedToken, "expected operand, found %s", describeToken(token)))
+	return &goast.BadExpr{From: token.Pos, To: token.Pos}, nil
+}
+
+// https://golang.org/ref/spec#Selectors
+// https://golang.org/ref/spec#Type_assertions
+func (p *Parser) eatSelectorOrTypeAssertion(x goast.Expr) (goast.Expr, error) {
+	if _, err := p.eat(gotoken.PERIOD); err != nil {
+		return nil, err
+	}
+	lparen, err := p.eat(gotoken.LPAREN)
+	if err != nil {
+		sel, err := p.eatIdent()
+		if err != nil {
+			return nil, err
+		}
+		return &goast.SelectorExpr{X: x, Sel: sel}, nil
+	}
+	assert := &goast.TypeAssertExpr{X: x, Lparen: lparen.Pos}
+	// https://golang.org/ref/spec#Type_switches
+	if _, err := p.eat(gotoken.TYPE); err != nil {
+		if assert.Type, err = p.eatType(); err != nil {
+			return nil, err
+		}
+	}
+	rparen, err := p.eat(gotoken.RPAREN)
+	if err != nil {
+		return nil, err
+	}
+	assert.Rparen = rparen.Pos
+	return assert, nil
+}
+
+// https://golang.org/ref/spec#Index_expressions
+// https://golang.org/ref/spec#Slice_expressions
+func (p *Parser) eatIndexOrSlice(x goast.Expr) (goast.Expr, error) {
+	lbrack, err := p.eat(gotoken.LBRACK)
+	if err != nil {
+		return nil, err
+	}
+	p.exprLev++
+	defer func() { p.exprLev-- }()
+
+	index := [3]goast.Expr{}
+	colons := []gotoken.Pos{}
+	if _, err := p.try(gotoken.COLON); err != nil {
+		if index[0], err = p.eatExpression(); err != nil {
+			return nil, err
+		}
+	}
+	for len(colons) < 2 {
+		colon, err := p.eat(gotoken.COLON)
+		if err != nil {
+			break
+		}
+		colons = append(colons, colon.Pos)
+		if p.atIndexEnd() {
+			continue
+		}
+		if index[len(colons)], err = p.eatExpression(); err != nil {
+			return nil, err
+		}
+	}
+	rbrack, err := p.eat(gotoken.RBRACK)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(colons) == 0 {
+		return &goast.IndexExpr{X: x, Lbrack: lbrack.Pos, Index: index[0], Rbrack: rbrack.Pos}, nil
+	}
+	slice := &goast.SliceExpr{X: x, Lbrack: lbrack.Pos, Low: index[0], High: index[1], Max: index[2], Rbrack: rbrack.Pos}
+	if len(colons) == 2 {
+		slice.Slice3 = true
+		if slice.High == nil || slice.Max == nil {
+			return nil, p.errorf(rbrack, MissingSliceIndex, "middle and final indices are required in a 3-index slice")
+		}
+	}
+	return slice, nil
+}
+
+func (p *Parser) atIndexEnd() bool {
+	token, err := p.currentToken()
+	return err != nil || token.Type == gotoken.COLON || token.Type == gotoken.RBRACK
+}
+
+// https://golang.org/ref/spec#Calls
+func (p *Parser) eatArguments(fun goast.Expr) (goast.Expr, error) {
+	lparen, err := p.eat(gotoken.LPAREN)
+	if err != nil {
+		return nil, err
+	}
+	call := &goast.CallExpr{Fun: fun, Lparen: lparen.Pos}
+	p.exprLev++
+	for {
+		if _, err := p.try(gotoken.RPAREN); err == nil {
+			break
+		}
+		arg, err := p.eatExpression()
+		if err != nil {
+			p.exprLev--
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+		// https://golang.org/ref/spec#Passing_arguments_to_..._parameters
+		if ellipsis, err := p.eat(gotoken.ELLIPSIS); err == nil {
+			call.Ellipsis = ellipsis.Pos
+		}
+		if _, err := p.eat(gotoken.COMMA); err != nil {
+			break
+		}
+	}
+	p.exprLev--
+	rparen, err := p.eat(gotoken.RPAREN)
+	if err != nil {
+		return nil, err
+	}
+	call.Rparen = rparen.Pos
+	return call, nil
+}
+
+// https://golang.org/ref/spec#Composite_literals
+func (p *Parser) eatLiteralValue(typ goast.Expr) (*goast.CompositeLit, error) {
+	lbrace, err := p.eat(gotoken.LBRACE)
+	if err != nil {
+		return nil, err
+	}
+	lit := &goast.CompositeLit{Type: typ, Lbrace: lbrace.Pos}
+	p.exprLev++
+	for {
+		if _, err := p.try(gotoken.RBRACE); err == nil {
+			break
+		}
+		elt, err := p.eatElement()
+		if err != nil {
+			p.exprLev--
+			return nil, err
+		}
+		if colon, err := p.eat(gotoken.COLON); err == nil {
+			value, err := p.eatElement()
+			if err != nil {
+				p.exprLev--
+				return nil, err
+			}
+			elt = &goast.KeyValueExpr{Key: elt, Colon: colon.Pos, Value: value}
+		}
+		lit.Elts = append(lit.Elts, elt)
+		if _, err := p.eat(gotoken.COMMA); err != nil {
+			break
+		}
+	}
+	p.exprLev--
+	rbrace, err := p.eat(gotoken.RBRACE)
+	if err != nil {
+		return nil, err
+	}
+	lit.Rbrace = rbrace.Pos
+	return lit, nil
+}
+
+// https://golang.org/ref/spec#Element
+func (p *Parser) eatElement() (goast.Expr, error) {
+	if _, err := p.try(gotoken.LBRACE); err == nil {
+		// The type of the literal is elided
+		return p.eatLiteralValue(nil)
+	}
+	return p.eatExpression()
+}
+
+// https://golang.org/ref/spec#Function_literals
+func (p *Parser) eatFuncTypeOrLit() (goast.Expr, error) {
+	typ, err := p.eatFuncType()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.try(gotoken.LBRACE); err != nil {
+		return typ, nil
+	}
+	p.exprLev++
+	body, err := p.eatBlockStmt()
+	p.exprLev--
+	if err != nil {
+		return nil, err
+	}
+	return &goast.FuncLit{Type: typ, Body: body}, nil
+}
+
+// https://golang.org/ref/spec#LiteralType
+func isLiteralType(x goast.Expr) bool {
+	switch t := x.(type) {
+	case *goast.Ident:
+	case *goast.SelectorExpr:
+		_, ok := t.X.(*goast.Ident)
+		return ok
+	case *goast.ArrayType:
+	case *goast.StructType:
+	case *goast.MapType:
+	default:
+		return false
+	}
+	return true
+}
+
+// isTypeName reports whether x may be a bare type name, which is ambiguous
+// with a block when followed by a brace.
+func isTypeName(x goast.Expr) bool {
+	switch t := x.(type) {
+	case *goast.Ident:
+		return true
+	case *goast.SelectorExpr:
+		_, ok := t.X.(*goast.Ident)
+		return ok
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package parser_test
+
+import (
+	"bytes"
+	gotoken "go/token"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"holang/pkg/lexer"
+	"holang/pkg/parser"
+)
+
+// FuzzParseFile checks that the parser neither panics nor runs forever,
+// whatever the input.
+func FuzzParseFile(f *testing.F) {
+	for _, pattern := range []string{"*.ho", "*.go"} {
+		paths, err := filepath.Glob(filepath.Join("..", "..", "samples", pattern))
+		if err != nil {
+			f.Fatal(err)
+		}
+		for _, path := range paths {
+			src, err := ioutil.ReadFile(path)
+			if err != nil {
+				f.Fatal(err)
+			}
+			f.Add(src)
+		}
+	}
+	f.Add([]byte("package p\nfunc f() { x := match y { 1 => 2, _ => 3 }; z **= 2 }"))
+	f.Add([]byte("package p\nenum E { A, B }\nfunc g(e E) { match e { A => {} } }"))
+	f.Fuzz(func(t *testing.T, src []byte) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			p := parser.NewParser(lexer.NewLexer(gotoken.NewFileSet(), bytes.NewReader(src), "fuzz.ho"))
+			p.ParseFile()
+		}()
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("ParseFile does not return on %q", src)
+		}
+	})
+}
@@ -0,0 +1,229 @@
+package parser
+
+import (
+	goast "go/ast"
+	gotoken "go/token"
+
+	"holang/pkg/lexer"
+	hotoken "holang/pkg/token"
+)
+
+// Match is a match construct, recorded so that later passes can complete its
+// lowering and check it.
+type Match struct {
+	Switch *goast.SwitchStmt
+	// Func is the function literal wrapping Switch when the match is used as
+	// an expression, nil otherwise. Its result type is a placeholder until
+	// the match is typed by lower.Package.
+	Func    *goast.FuncLit
+	keyword lexer.Token
+}
+
+// Default returns the clause of the `_` arm, nil if there is none.
+func (m *Match) Default() *goast.CaseClause {
+	for _, stmt := range m.Switch.Body.List {
+		if clause := stmt.(*goast.CaseClause); clause.List == nil {
+			return clause
+		}
+	}
+	return nil
+}
+
+// eatMatchStmt parses a match used as a statement, lowered to a switch:
+//
+//	match x {                   switch x {
+//		A, B => f(),        ->  case A, B:
+//		C => { ... }                f()
+//		_ => g()                case C:
+//	}                               ...
+//	                            default:
+//	                                g()
+//	                            }
+func (p *Parser) eatMatchStmt() (goast.Stmt, error) {
+	m, err := p.eatMatch(false)
+	if err != nil {
+		return nil, err
+	}
+	return m.Switch, nil
+}
+
+// eatMatchExpr parses a match used as an expression, lowered to a switch
+// returning the value of its arms from an immediately invoked function
+// literal:
+//
+//	match x {               func() T {
+//		A => 1,                 switch x {
+//		B => 2,     ->          case A:
+//	}                               return 1
+//	                            case B:
+//	                                return 2
+//	                            }
+//	                            panic("no match arm for the value")
+//	                        }()
+func (p *Parser) eatMatchExpr() (goast.Expr, error) {
+	m, err := p.eatMatch(true)
+	if err != nil {
+		return nil, err
+	}
+	body := &goast.BlockStmt{
+		Lbrace: m.Switch.Body.Lbrace,
+		List:   []goast.Stmt{m.Switch},
+		Rbrace: m.Switch.Body.Rbrace,
+	}
+	if m.Default() == nil {
+		body.List = append(body.List, &goast.ExprStmt{X: &goast.CallExpr{
+			Fun:  goast.NewIdent("panic"),
+			Args: []goast.Expr{stringLit("no match arm for the value")},
+		}})
+	}
+	m.Func = &goast.FuncLit{
+		Type: &goast.FuncType{
+			Func:   m.keyword.Pos,
+			Params: fields(),
+			Results: fields(field("", &goast.InterfaceType{
+				Interface: m.keyword.Pos,
+				Methods:   &goast.FieldList{Opening: m.keyword.Pos, Closing: m.keyword.Pos},
+			})),
+		},
+		Body: body,
+	}
+	return &goast.CallExpr{Fun: m.Func, Lparen: m.Switch.Body.Rbrace, Rparen: m.Switch.Body.Rbrace}, nil
+}
+
+// https://golang.org/ref/spec#Switch_statements
+//
+// Match = "match" [ Expression ] "{" { Arm ( "," | ";" ) } "}" .
+// Arm   = ( ExpressionList | "_" ) "=>" ( Expression | Block ) .
+//
+// Blocks are only allowed as arms of match statements.
+func (p *Parser) eatMatch(isExpr bool) (*Match, error) {
+	keyword, err := p.eat(hotoken.MATCH)
+	if err != nil {
+		return nil, err
+	}
+	m := &Match{keyword: keyword}
+	m.Switch = &goast.SwitchStmt{Switch: keyword.Pos}
+
+	if _, err := p.try(gotoken.LBRACE); err != nil {
+		prevLev := p.exprLev
+		p.exprLev = -1
+		m.Switch.Tag, err = p.eatExpression()
+		p.exprLev = prevLev
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	lbrace, err := p.eat(gotoken.LBRACE)
+	if err != nil {
+		return nil, err
+	}
+	m.Switch.Body = &goast.BlockStmt{Lbrace: lbrace.Pos}
+	prevLev := p.exprLev
+	p.exprLev = 0
+	defer func() { p.exprLev = prevLev }()
+	// The closing brace of the previous arm if it is a block, which lowering
+	// drops: what follows it on the next line is moved up to it, for no
+	// empty line to be printed in its place
+	var end *lexer.Token
+	follows := func(token lexer.Token) gotoken.Pos {
+		if end != nil && token.Position.Line == end.Position.Line+1 {
+			return end.Pos
+		}
+		return token.Pos
+	}
+	for {
+		if rbrace, err := p.eat(gotoken.RBRACE); err == nil {
+			m.Switch.Body.Rbrace = follows(rbrace)
+			break
+		}
+		start, err := p.currentToken()
+		if err != nil {
+			return nil, err
+		}
+		arm, err := p.eatMatchArm(isExpr)
+		if err != nil {
+			return nil, err
+		}
+		arm.Case = follows(start)
+		end = nil
+		if last := p.last; last.Type == gotoken.RBRACE && !isExpr {
+			end = &last
+		}
+		if arm.List == nil && m.Default() != nil {
+			// Recorded only, like an arm mixing `_` with patterns
+			p.error(p.errorf(start, DuplicateDefault, "multiple `_` arms in match"))
+		}
+		m.Switch.Body.List = append(m.Switch.Body.List, arm)
+		if _, err := p.try(gotoken.RBRACE); err == nil {
+			continue
+		}
+		if _, err := p.eat(gotoken.COMMA); err == nil {
+			continue
+		}
+		if _, err := p.eat(gotoken.SEMICOLON); err != nil {
+			return nil, err
+		}
+	}
+	p.matches = append(p.matches, m)
+	return m, nil
+}
+
+func (p *Parser) eatMatchArm(isExpr bool) (*goast.CaseClause, error) {
+	start, err := p.currentToken()
+	if err != nil {
+		return nil, err
+	}
+	clause := &goast.CaseClause{Case: start.Pos}
+	patterns := []goast.Expr{}
+	var blank *lexer.Token // first `_` pattern
+	for {
+		token, err := p.currentToken()
+		if err != nil {
+			return nil, err
+		}
+		x, err := p.eatExpression()
+		if err != nil {
+			return nil, err
+		}
+		if ident, ok := x.(*goast.Ident); ok && ident.Name == "_" && blank == nil {
+			blank = &token
+		}
+		patterns = append(patterns, x)
+		if _, err := p.eat(gotoken.COMMA); err != nil {
+			break
+		}
+	}
+	if blank != nil && len(patterns) > 1 {
+		// The arm would both be the default one and match some values. The
+		// rest of the arm parses fine, so the error is only recorded
+		p.error(p.errorf(*blank, BlankPattern, "`_` must be the only pattern of its arm"))
+	}
+	if blank == nil || len(patterns) > 1 {
+		clause.List = patterns
+	}
+	arrow, err := p.eat(hotoken.FAT_ARROW)
+	if err != nil {
+		return nil, err
+	}
+	clause.Colon = arrow.Pos
+
+	if _, err := p.try(gotoken.LBRACE); err == nil && !isExpr {
+		block, err := p.eatBlockStmt()
+		if err != nil {
+			return nil, err
+		}
+		clause.Body = block.List
+		return clause, nil
+	}
+	x, err := p.eatExpression()
+	if err != nil {
+		return nil, err
+	}
+	if isExpr {
+		clause.Body = []goast.Stmt{&goast.ReturnStmt{Return: x.Pos(), Results: []goast.Expr{x}}}
+	} else {
+		clause.Body = []goast.Stmt{&goast.ExprStmt{X: x}}
+	}
+	return clause, nil
+}
@@ -1,121 +1,158 @@
 package parser
 
 import (
-	"errors"
-	"fmt"
 	goast "go/ast"
 	gotoken "go/token"
 
+	"holang/pkg/astutil"
+	"holang/pkg/diagnostics"
 	"holang/pkg/lexer"
+	hotoken "holang/pkg/token"
 )
 
 type Parser struct {
-	tokens []lexer.Token
-	index  int
+	lexer    *lexer.Lexer
+	tokens   []lexer.Token   // lookahead buffer, starting with the current token
+	comments []*commentGroup // comments met so far, kept out of the token stream
+	prev     *lexer.Token    // last token read from the lexer, comments aside
+	last     lexer.Token     // last token consumed
+	exprLev  int             // < 0: in a control clause, >= 0: in an expression
+	imports  []*goast.ImportSpec
+	injected []*goast.ImportSpec // imports required by lowered holang constructs
+	enums    []*Enum
+	matches  []*Match
+	errors   diagnostics.List
 }
 
-func NewParser(tokens []lexer.Token) Parser {
+func NewParser(l *lexer.Lexer) Parser {
 	return Parser{
-		tokens: tokens,
-		index:  0,
+		lexer:    l,
+		tokens:   []lexer.Token{},
+		comments: []*commentGroup{},
+		imports:  []*goast.ImportSpec{},
+		injected: []*goast.ImportSpec{},
+		enums:    []*Enum{},
+		matches:  []*Match{},
+		errors:   diagnostics.List{},
 	}
 }
 
+// Constructs are the holang constructs of a file, recorded for the passes
+// completing their lowering and checking them.
+type Constructs struct {
+	Enums   []*Enum
+	Matches []*Match
+}
+
+// Constructs returns the holang constructs of the file parsed so far.
+func (p *Parser) Constructs() Constructs {
+	return Constructs{Enums: p.enums, Matches: p.matches}
+}
+
 // https://golang.org/ref/spec#Source_file_organization
+//
+// ParseFile recovers from syntax errors, replacing the declarations and
+// statements it could not parse by BadDecl and BadStmt nodes, and returns the
+// file along with a diagnostics.List of every error.
 func (p *Parser) ParseFile() (goast.File, error) {
-	p.eatComments()
-
 	// https://golang.org/ref/spec#Package_clause
 
-	name, err := p.eatPackageClause()
-	if err != nil {
-		return goast.File{}, err
+	pkg, name, err := p.eatPackageClause()
+	if err == nil {
+		_, err = p.eat(gotoken.SEMICOLON)
 	}
-
-	_, err = p.eat(gotoken.SEMICOLON)
 	if err != nil {
-		return goast.File{}, err
+		p.error(err)
+		return goast.File{Package: pkg.Pos, Name: &name}, p.errors.Err()
 	}
 
 	// https://golang.org/ref/spec#Import_declarations
 
-	imports := []*goast.ImportSpec{}
+	decls := []goast.Decl{}
 
 	for {
-		if _, err := p.try(gotoken.IMPORT); err != nil {
+		start, err := p.try(gotoken.IMPORT)
+		if err != nil {
 			break
 		}
-		specs, err := p.eatImportDecl()
+		decl, err := p.eatImportDecl()
+		if err == nil {
+			_, err = p.eat(gotoken.SEMICOLON)
+		}
 		if err != nil {
-			return goast.File{}, err
+			decls = append(decls, p.badDecl(start, err))
+			continue
 		}
-		imports = append(imports, specs...)
-		if _, err = p.eat(gotoken.SEMICOLON); err != nil {
-			return goast.File{}, err
+		decls = append(decls, decl)
+		for _, spec := range decl.Specs {
+			p.imports = append(p.imports, spec.(*goast.ImportSpec))
 		}
 	}
 
 	// https://golang.org/ref/spec#TopLevelDecl
 
-	decls := []goast.Decl{}
-
-	return goast.File{
-		Name:    &name,
-		Decls:   decls,
-		Imports: imports,
-	}, nil
-}
-
-func (p *Parser) eatComments() ([]lexer.Token, error) {
-	comments := []lexer.Token{}
-	for {
-		comment, err := p.eat(gotoken.COMMENT)
+	for !p.eof() {
+		start, _ := p.currentToken()
+		var declared []goast.Decl
+		if _, err = p.try(hotoken.ENUM); err == nil {
+			declared, err = p.eatEnumDecl()
+		} else {
+			var decl goast.Decl
+			if decl, err = p.eatTopLevelDecl(); err == nil {
+				declared = []goast.Decl{decl}
+			}
+		}
+		if err == nil {
+			_, err = p.eat(gotoken.SEMICOLON)
+		}
 		if err != nil {
-			break
+			decls = append(decls, p.badDecl(start, err))
+			continue
 		}
-		comments = append(comments, comment)
+		decls = append(decls, declared...)
 	}
-	return comments, nil
-}
 
-func (p *Parser) eatImportDecl() ([]*goast.ImportSpec, error) {
-	specs := []*goast.ImportSpec{}
-	if _, err := p.eat(gotoken.IMPORT); err != nil {
-		return specs, err
+	file := goast.File{
+		Doc:      p.leadComment(pkg),
+		Comments: p.fileComments(),
+		Package:  pkg.Pos,
+		Name:     &name,
+		Decls:    decls,
+		Imports:  p.imports,
 	}
-	if _, err := p.eat(gotoken.LPAREN); err == nil {
-		for {
-			if _, err := p.eat(gotoken.RPAREN); err == nil {
-				return specs, nil
-			}
-			spec, err := p.eatImportSpec()
-			if err != nil {
-				return specs, err
-			}
-			specs = append(specs, spec)
-			if _, err = p.eat(gotoken.SEMICOLON); err != nil {
-				return specs, err
-			}
-		}
+	for _, spec := range p.injected {
+		astutil.AddImport(&file, spec)
 	}
-	spec, err := p.eatImportSpec()
-	if err != nil {
-		return specs, err
+	p.errors.Sort()
+	return file, p.errors.Err()
+}
+
+// useImport returns the name under which the package at path can be
+// referred to by lowered code, importing it if the file does not already.
+func (p *Parser) useImport(path string) *goast.Ident {
+	name, spec := astutil.Import(append(p.imports, p.injected...), path)
+	if spec != nil {
+		p.injected = append(p.injected, spec)
 	}
-	specs = append(specs, spec)
-	return specs, nil
+	return name
+}
+
+func (p *Parser) eatImportDecl() (*goast.GenDecl, error) {
+	return p.eatGenDecl(gotoken.IMPORT, func(int) (goast.Spec, error) {
+		return p.eatImportSpec()
+	})
 }
 
 func (p *Parser) eatImportSpec() (*goast.ImportSpec, error) {
 	spec := goast.ImportSpec{}
 	if name, err := p.eat(gotoken.PERIOD); err == nil {
-		spec.Name = &goast.Ident{Name: name.Value}
+		spec.Name = &goast.Ident{NamePos: name.Pos, Name: name.Value}
 	} else if name, err := p.try(gotoken.IDENT); err == nil && name.IsBlankIdentifier() {
 		p.next()
-		spec.Name = &goast.Ident{Name: name.Value}
+		spec.Name = &goast.Ident{NamePos: name.Pos, Name: name.Value}
 	} else if packageName, err := p.tryPackageName(); err == nil {
 		p.next()
-		spec.Name = &goast.Ident{Name: packageName.Value}
+		spec.Name = &goast.Ident{NamePos: packageName.Pos, Name: packageName.Value}
 	} else {
 		spec.Name = nil
 	}
@@ -133,21 +170,22 @@ func (p *Parser) eatImportPath() (goast.BasicLit, error) {
 		return goast.BasicLit{}, err
 	}
 	return goast.BasicLit{
-		Kind:  path.Type,
-		Value: path.Value,
+		ValuePos: path.Pos,
+		Kind:     path.Type,
+		Value:    path.Value,
 	}, nil
 }
 
-func (p *Parser) eatPackageClause() (goast.Ident, error) {
-	_, err := p.eat(gotoken.PACKAGE)
+func (p *Parser) eatPackageClause() (lexer.Token, goast.Ident, error) {
+	keyword, err := p.eat(gotoken.PACKAGE)
 	if err != nil {
-		return goast.Ident{}, err
+		return lexer.Token{}, goast.Ident{}, err
 	}
 	ident, err := p.eatPackageName()
 	if err != nil {
-		return goast.Ident{}, err
+		return lexer.Token{}, goast.Ident{}, err
 	}
-	return goast.Ident{Name: ident.Value}, nil
+	return keyword, goast.Ident{NamePos: ident.Pos, Name: ident.Value}, nil
 }
 
 func (p *Parser) eatPackageName() (lexer.Token, error) {
@@ -165,7 +203,7 @@ func (p *Parser) tryPackageName() (lexer.Token, error) {
 		return lexer.Token{}, err
 	}
 	if ident.IsBlankIdentifier() {
-		return lexer.Token{}, errors.New("The package name must not be the blank identifier")
+		return lexer.Token{}, p.errorf(ident, BlankPackageName, "the package name must not be the blank identifier")
 	}
 	return ident, nil
 }
@@ -185,21 +223,61 @@ func (p *Parser) try(_type gotoken.Token) (lexer.Token, error) {
 		return lexer.Token{}, err
 	}
 	if token.Type != _type {
-		return lexer.Token{}, fmt.Errorf("Expected %s token, but got %s at: %+v", _type, token.Type, token)
+		return lexer.Token{}, p.errorf(token, UnexpectedToken, "expected %s, found %s", describe(_type), describeToken(token))
 	}
 	return token, nil
 }
 
 func (p *Parser) next() {
-	if p.index > len(p.tokens) {
-		panic("Implementation error: should not be able to go beyond the size of the tokens list")
+	if len(p.tokens) == 0 {
+		panic("Implementation error: should not be able to go beyond a token that has not been read")
 	}
-	p.index++
+	p.last = p.tokens[0]
+	p.tokens = p.tokens[1:]
+}
+
+// splitPow splits the current token, a `**` standing for two stars, into two
+// `*` tokens: the lexer reads adjacent stars as the power operator.
+func (p *Parser) splitPow() {
+	first := p.tokens[0]
+	second := first
+	first.Type, first.Value = gotoken.MUL, "*"
+	first.End = first.Position
+	first.End.Offset++
+	first.End.Column++
+	second.Type, second.Value = gotoken.MUL, "*"
+	second.Position = first.End
+	if second.Pos.IsValid() {
+		second.Pos++
+	}
+	p.tokens = append([]lexer.Token{first, second}, p.tokens[1:]...)
 }
 
 func (p *Parser) currentToken() (lexer.Token, error) {
-	if p.index == len(p.tokens) {
-		return lexer.Token{}, errors.New("Reached end of file")
+	return p.peek(0)
+}
+
+// peek returns the n-th token after the current one, pulling tokens from the
+// lexer into the lookahead buffer as needed.
+func (p *Parser) peek(n int) (lexer.Token, error) {
+	for len(p.tokens) <= n {
+		token := p.lexer.Next()
+		if token.Type == gotoken.COMMENT {
+			p.addComment(token)
+			continue
+		}
+		p.addToken(token)
+		p.tokens = append(p.tokens, token)
+	}
+	token := p.tokens[n]
+	if token.Type == gotoken.EOF {
+		return lexer.Token{}, p.errorf(token, UnexpectedEOF, "unexpected end of file")
 	}
-	return p.tokens[p.index], nil
+	return token, nil
+}
+
+// eof reports whether all the tokens have been consumed.
+func (p *Parser) eof() bool {
+	_, err := p.currentToken()
+	return err != nil
 }
@@ -0,0 +1,89 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	"holang/pkg/conformance"
+	"holang/pkg/diagnostics"
+	"holang/pkg/lexer"
+	"holang/pkg/parser"
+)
+
+// TestStars checks that adjacent stars, lexed as the power operator, are
+// parsed like go/parser does where no power is meant, positions included.
+func TestStars(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"variable type", "package p\n\nvar q **int\n"},
+		{"parameter and result types", "package p\n\nfunc f(q **int, r ...**int) **int { return q }\n"},
+		{"field type", "package p\n\ntype T struct{ next **T }\n"},
+		{"element types", "package p\n\nvar m map[**int][]***int\n"},
+		{"dereferences", "package p\n\nfunc f(q **int) int { **q = 1; return **q + ***&q }\n"},
+		{"conversion", "package p\n\nvar q = (**int)(nil)\n"},
+		{"composite literal", "package p\n\nvar s = []**int{nil}\n"},
+		{"type switch", "package p\n\nfunc f(x interface{}) {\n\tswitch x.(type) {\n\tcase **int:\n\t}\n}\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := conformance.File("p.go", []byte(test.src), conformance.Positions); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// TestTypeSwitchCases checks that the cases of type switches are parsed as
+// lists of types, the types which are no expressions included.
+func TestTypeSwitchCases(t *testing.T) {
+	tests := []struct {
+		name string
+		typ  string
+	}{
+		{"function", "func(int) (string, error)"},
+		{"channels", "chan int, <-chan int, chan<- **int"},
+		{"interface", "interface{ M() }"},
+		{"composite types", "[]int, [2]int, map[string]int, struct{ x int }"},
+		{"parenthesized", "(*int), (func())"},
+		{"nil", "nil, error"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			src := "package p\n\nfunc f(x interface{}) {\n\tswitch y := x.(type) {\n\tcase " + test.typ + ":\n\t\t_ = y\n\t}\n}\n"
+			if err := conformance.File("p.go", []byte(src), conformance.Positions); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestMatchArms(t *testing.T) {
+	tests := []struct {
+		name  string
+		arms  string
+		codes []string // codes of the errors, if any
+	}{
+		{"patterns", "1, 2 => a(); _ => b()", nil},
+		{"blank after patterns", "1, _ => a()", []string{parser.BlankPattern}},
+		{"blank before patterns", "_, 1 => a()", []string{parser.BlankPattern}},
+		{"blank and default", "1, _ => a(); _ => b()", []string{parser.BlankPattern}},
+		{"two defaults", "_ => a(); _ => b()", []string{parser.DuplicateDefault}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			src := "package p\n\nfunc f(x int) {\n\tmatch x {\n\t\t" + strings.Replace(test.arms, "; ", "\n\t\t", -1) + "\n\t}\n}\n"
+			p := parser.NewParser(lexer.NewLexer(nil, strings.NewReader(src), "p.ho"))
+			_, err := p.ParseFile()
+			list, _ := err.(diagnostics.List)
+			codes := []string{}
+			for _, d := range list {
+				codes = append(codes, d.Code)
+			}
+			if strings.Join(codes, " ") != strings.Join(test.codes, " ") {
+				t.Errorf("got errors %v, want %v", err, test.codes)
+			}
+		})
+	}
+}
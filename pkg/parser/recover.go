@@ -0,0 +1,114 @@
+package parser
+
+import (
+	goast "go/ast"
+	gotoken "go/token"
+
+	"holang/pkg/diagnostics"
+	"holang/pkg/lexer"
+	hotoken "holang/pkg/token"
+)
+
+// error records err, a syntax error the parser recovers from. Like
+// go/parser, only the first error of a line is kept: the following ones are
+// most likely caused by it.
+func (p *Parser) error(err error) {
+	switch err := err.(type) {
+	case *diagnostics.Diagnostic:
+		if n := len(p.errors); n > 0 {
+			last := p.errors[n-1].Primary.Start
+			if last.Filename == err.Primary.Start.Filename && last.Line == err.Primary.Start.Line {
+				return
+			}
+		}
+		p.errors = append(p.errors, err)
+	case diagnostics.List:
+		for _, d := range err {
+			p.error(d)
+		}
+	default:
+		p.errors = append(p.errors, &diagnostics.Diagnostic{Severity: diagnostics.Error, Message: err.Error()})
+	}
+}
+
+// badDecl records err, met while parsing the declaration starting with
+// start, skips to the next declaration and returns the BadDecl standing for
+// the skipped source.
+func (p *Parser) badDecl(start lexer.Token, err error) *goast.BadDecl {
+	p.error(err)
+	if token, err := p.currentToken(); err == nil && token.Position.Offset == start.Position.Offset {
+		p.next()
+	}
+	p.syncDecl()
+	return &goast.BadDecl{From: start.Pos, To: p.pos()}
+}
+
+// badStmt records err, met while parsing the statement starting with start,
+// skips to the end of the statement and returns the BadStmt standing for the
+// skipped source.
+func (p *Parser) badStmt(start lexer.Token, err error) *goast.BadStmt {
+	p.error(err)
+	p.syncStmt()
+	return &goast.BadStmt{From: start.Pos, To: p.pos()}
+}
+
+// syncDecl skips the tokens up to the next top-level declaration, told by
+// its keyword starting a line, as gofmt puts it.
+func (p *Parser) syncDecl() {
+	for {
+		token, err := p.currentToken()
+		if err != nil {
+			return
+		}
+		switch token.Type {
+		case gotoken.CONST, gotoken.TYPE, gotoken.VAR, gotoken.FUNC, gotoken.IMPORT, hotoken.ENUM:
+			if token.Position.Column == 1 {
+				return
+			}
+		}
+		p.next()
+	}
+}
+
+// syncStmt skips the tokens up to the end of the current statement, outside
+// of any brackets: a semicolon, which is consumed, or the closing brace or
+// the next clause of the enclosing block, which are not.
+func (p *Parser) syncStmt() {
+	depth := 0
+	for {
+		token, err := p.currentToken()
+		if err != nil {
+			return
+		}
+		switch token.Type {
+		case gotoken.LPAREN, gotoken.LBRACK, gotoken.LBRACE:
+			depth++
+		case gotoken.RPAREN, gotoken.RBRACK:
+			if depth > 0 {
+				depth--
+			}
+		case gotoken.RBRACE:
+			if depth == 0 {
+				return
+			}
+			depth--
+		case gotoken.CASE, gotoken.DEFAULT:
+			if depth == 0 {
+				return
+			}
+		case gotoken.SEMICOLON:
+			if depth == 0 {
+				p.next()
+				return
+			}
+		}
+		p.next()
+	}
+}
+
+// pos returns the position of the current token, that of the end of the
+// file once reached.
+func (p *Parser) pos() gotoken.Pos {
+	p.peek(0)
+	return p.tokens[0].Pos
+}
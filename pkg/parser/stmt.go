@@ -0,0 +1,582 @@
+package parser
+
+import (
+	goast "go/ast"
+	gotoken "go/token"
+
+	hotoken "holang/pkg/token"
+)
+
+// https://golang.org/ref/spec#Blocks
+func (p *Parser) eatBlockStmt() (*goast.BlockStmt, error) {
+	lbrace, err := p.eat(gotoken.LBRACE)
+	if err != nil {
+		return nil, err
+	}
+	list := p.eatStatementList()
+	rbrace, err := p.eat(gotoken.RBRACE)
+	if err != nil {
+		return nil, err
+	}
+	return &goast.BlockStmt{Lbrace: lbrace.Pos, List: list, Rbrace: rbrace.Pos}, nil
+}
+
+// https://golang.org/ref/spec#StatementList
+//
+// The statements that cannot be parsed are recorded as errors and replaced by
+// BadStmt nodes.
+func (p *Parser) eatStatementList() []goast.Stmt {
+	list := []goast.Stmt{}
+	for {
+		if p.atStatementListEnd() {
+			return list
+		}
+		start, _ := p.currentToken()
+		stmt, err := p.eatStatement()
+		if err != nil {
+			list = append(list, p.badStmt(start, err))
+			continue
+		}
+		list = append(list, stmt)
+		if _, ok := stmt.(*goast.EmptyStmt); ok {
+			continue
+		}
+		// The semicolon may be omitted before a closing brace
+		if p.atStatementListEnd() {
+			continue
+		}
+		if _, err := p.eat(gotoken.SEMICOLON); err != nil {
+			p.error(err)
+			p.syncStmt()
+		}
+	}
+}
+
+func (p *Parser) atStatementListEnd() bool {
+	token, err := p.currentToken()
+	if err != nil {
+		return true
+	}
+	switch token.Type {
+	case gotoken.RBRACE, gotoken.CASE, gotoken.DEFAULT:
+		return true
+	}
+	return false
+}
+
+// https://golang.org/ref/spec#Statement
+func (p *Parser) eatStatement() (goast.Stmt, error) {
+	token, err := p.currentToken()
+	if err != nil {
+		return nil, err
+	}
+	switch token.Type {
+	case gotoken.CONST, gotoken.TYPE, gotoken.VAR:
+		decl, err := p.eatDeclaration()
+		if err != nil {
+			return nil, err
+		}
+		return &goast.DeclStmt{Decl: decl}, nil
+	case gotoken.GO, gotoken.DEFER:
+		return p.eatGoOrDeferStmt()
+	case gotoken.RETURN:
+		return p.eatReturnStmt()
+	case gotoken.BREAK, gotoken.CONTINUE, gotoken.GOTO, gotoken.FALLTHROUGH:
+		return p.eatBranchStmt()
+	case gotoken.LBRACE:
+		return p.eatBlockStmt()
+	case gotoken.IF:
+		return p.eatIfStmt()
+	case gotoken.SWITCH:
+		return p.eatSwitchStmt()
+	case gotoken.SELECT:
+		return p.eatSelectStmt()
+	case gotoken.FOR:
+		return p.eatForStmt()
+	case hotoken.MATCH:
+		return p.eatMatchStmt()
+	case gotoken.SEMICOLON:
+		// https://golang.org/ref/spec#Empty_statements
+		p.next()
+		return &goast.EmptyStmt{Semicolon: token.Pos, Implicit: token.Value == "\n"}, nil
+	}
+	return p.eatSimpleStmt(labelOk)
+}
+
+// What eatSimpleStmt may accept besides simple statements
+const (
+	basic = iota
+	labelOk
+	rangeOk
+)
+
+// https://golang.org/ref/spec#SimpleStmt
+//
+// A range clause is returned as an assignment of a RANGE UnaryExpr, which the
+// caller turns into a RangeStmt.
+func (p *Parser) eatSimpleStmt(mode int) (goast.Stmt, error) {
+	if mode == rangeOk {
+		if keyword, err := p.eat(gotoken.RANGE); err == nil {
+			x, err := p.eatExpression()
+			if err != nil {
+				return nil, err
+			}
+			rhs := &goast.UnaryExpr{OpPos: keyword.Pos, Op: gotoken.RANGE, X: x}
+			return &goast.AssignStmt{Rhs: []goast.Expr{rhs}}, nil
+		}
+	}
+
+	lhs, err := p.eatExpressionList()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := p.currentToken()
+	if err != nil {
+		return &goast.ExprStmt{X: lhs[0]}, nil
+	}
+	switch token.Type {
+	case gotoken.DEFINE, gotoken.ASSIGN, gotoken.ADD_ASSIGN, gotoken.SUB_ASSIGN,
+		gotoken.MUL_ASSIGN, gotoken.QUO_ASSIGN, gotoken.REM_ASSIGN, gotoken.AND_ASSIGN,
+		gotoken.OR_ASSIGN, gotoken.XOR_ASSIGN, gotoken.SHL_ASSIGN, gotoken.SHR_ASSIGN,
+		gotoken.AND_NOT_ASSIGN, hotoken.POW_ASSIGN:
+		// https://golang.org/ref/spec#Assignments
+		// https://golang.org/ref/spec#Short_variable_declarations
+		p.next()
+		stmt := &goast.AssignStmt{Lhs: lhs, TokPos: token.Pos, Tok: token.Type}
+		if keyword, err := p.eat(gotoken.RANGE); err == nil && mode == rangeOk &&
+			(token.Type == gotoken.DEFINE || token.Type == gotoken.ASSIGN) {
+			x, err := p.eatExpression()
+			if err != nil {
+				return nil, err
+			}
+			stmt.Rhs = []goast.Expr{&goast.UnaryExpr{OpPos: keyword.Pos, Op: gotoken.RANGE, X: x}}
+			return stmt, nil
+		} else if err == nil {
+			return nil, p.errorf(keyword, UnexpectedToken, "unexpected range")
+		}
+		if stmt.Rhs, err = p.eatExpressionList(); err != nil {
+			return nil, err
+		}
+		return stmt, nil
+	}
+
+	if len(lhs) > 1 {
+		return nil, p.errorf(token, UnexpectedToken, "expected 1 expression, found %d", len(lhs))
+	}
+
+	switch token.Type {
+	case gotoken.COLON:
+		// https://golang.org/ref/spec#Labeled_statements
+		label, ok := lhs[0].(*goast.Ident)
+		if mode != labelOk || !ok {
+			break
+		}
+		p.next()
+		stmt := &goast.LabeledStmt{Label: label, Colon: token.Pos}
+		if _, err := p.try(gotoken.RBRACE); err == nil {
+			// A label right before a closing brace labels an empty statement
+			stmt.Stmt = &goast.EmptyStmt{Semicolon: token.EndPos(), Implicit: true}
+			return stmt, nil
+		}
+		if stmt.Stmt, err = p.eatStatement(); err != nil {
+			return nil, err
+		}
+		return stmt, nil
+	case gotoken.ARROW:
+		// https://golang.org/ref/spec#Send_statements
+		p.next()
+		value, err := p.eatExpression()
+		if err != nil {
+			return nil, err
+		}
+		return &goast.SendStmt{Chan: lhs[0], Arrow: token.Pos, Value: value}, nil
+	case gotoken.INC, gotoken.DEC:
+		// https://golang.org/ref/spec#IncDec_statements
+		p.next()
+		return &goast.IncDecStmt{X: lhs[0], TokPos: token.Pos, Tok: token.Type}, nil
+	}
+
+	// https://golang.org/ref/spec#Expression_statements
+	return &goast.ExprStmt{X: lhs[0]}, nil
+}
+
+// https://golang.org/ref/spec#Go_statements
+// https://golang.org/ref/spec#Defer_statements
+func (p *Parser) eatGoOrDeferStmt() (goast.Stmt, error) {
+	keyword, err := p.currentToken()
+	if err != nil {
+		return nil, err
+	}
+	p.next()
+	start, err := p.currentToken()
+	if err != nil {
+		return nil, err
+	}
+	x, err := p.eatExpression()
+	if err != nil {
+		return nil, err
+	}
+	call, ok := x.(*goast.CallExpr)
+	if !ok {
+		return nil, p.errorf(start, ExpectedCall, "expression in %s must be function call", keyword.Value)
+	}
+	if keyword.Type == gotoken.GO {
+		return &goast.GoStmt{Go: keyword.Pos, Call: call}, nil
+	}
+	return &goast.DeferStmt{Defer: keyword.Pos, Call: call}, nil
+}
+
+// https://golang.org/ref/spec#Return_statements
+func (p *Parser) eatReturnStmt() (goast.Stmt, error) {
+	keyword, err := p.eat(gotoken.RETURN)
+	if err != nil {
+		return nil, err
+	}
+	stmt := &goast.ReturnStmt{Return: keyword.Pos}
+	if _, err := p.try(gotoken.SEMICOLON); err == nil {
+		return stmt, nil
+	}
+	if _, err := p.try(gotoken.RBRACE); err == nil {
+		return stmt, nil
+	}
+	if stmt.Results, err = p.eatExpressionList(); err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+// https://golang.org/ref/spec#Break_statements
+// https://golang.org/ref/spec#Continue_statements
+// https://golang.org/ref/spec#Goto_statements
+// https://golang.org/ref/spec#Fallthrough_statements
+func (p *Parser) eatBranchStmt() (goast.Stmt, error) {
+	keyword, err := p.currentToken()
+	if err != nil {
+		return nil, err
+	}
+	p.next()
+	stmt := &goast.BranchStmt{TokPos: keyword.Pos, Tok: keyword.Type}
+	if keyword.Type == gotoken.FALLTHROUGH {
+		return stmt, nil
+	}
+	if keyword.Type == gotoken.GOTO {
+		stmt.Label, err = p.eatIdent()
+		return stmt, err
+	}
+	if _, err := p.try(gotoken.IDENT); err == nil {
+		stmt.Label, _ = p.eatIdent()
+	}
+	return stmt, nil
+}
+
+// https://golang.org/ref/spec#If_statements
+func (p *Parser) eatIfStmt() (goast.Stmt, error) {
+	keyword, err := p.eat(gotoken.IF)
+	if err != nil {
+		return nil, err
+	}
+	stmt := &goast.IfStmt{If: keyword.Pos}
+
+	prevLev := p.exprLev
+	p.exprLev = -1
+	init, cond, err := p.eatControlClause()
+	p.exprLev = prevLev
+	if err != nil {
+		return nil, err
+	}
+	condition, ok := cond.(*goast.ExprStmt)
+	if !ok {
+		token, _ := p.currentToken()
+		return nil, p.errorf(token, MissingCondition, "missing condition in if statement")
+	}
+	stmt.Init, stmt.Cond = init, condition.X
+
+	if stmt.Body, err = p.eatBlockStmt(); err != nil {
+		return nil, err
+	}
+	if _, err := p.eat(gotoken.ELSE); err != nil {
+		return stmt, nil
+	}
+	if _, err := p.try(gotoken.IF); err == nil {
+		stmt.Else, err = p.eatIfStmt()
+	} else {
+		stmt.Else, err = p.eatBlockStmt()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+// eatControlClause parses the `[SimpleStmt ;] [SimpleStmt]` header of if and
+// switch statements, up to the opening brace of their body.
+func (p *Parser) eatControlClause() (init goast.Stmt, stmt goast.Stmt, err error) {
+	if _, err := p.try(gotoken.LBRACE); err == nil {
+		return nil, nil, nil
+	}
+	if _, err := p.try(gotoken.SEMICOLON); err != nil {
+		if stmt, err = p.eatSimpleStmt(basic); err != nil {
+			return nil, nil, err
+		}
+	}
+	if semicolon, err := p.eat(gotoken.SEMICOLON); err == nil {
+		if semicolon.Value == "\n" {
+			return nil, nil, p.errorf(semicolon, UnexpectedToken, "unexpected newline, expected { after clause")
+		}
+		init, stmt = stmt, nil
+		if _, err := p.try(gotoken.LBRACE); err != nil {
+			if stmt, err = p.eatSimpleStmt(basic); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return init, stmt, nil
+}
+
+// https://golang.org/ref/spec#Switch_statements
+func (p *Parser) eatSwitchStmt() (goast.Stmt, error) {
+	keyword, err := p.eat(gotoken.SWITCH)
+	if err != nil {
+		return nil, err
+	}
+
+	prevLev := p.exprLev
+	p.exprLev = -1
+	init, tag, err := p.eatControlClause()
+	p.exprLev = prevLev
+	if err != nil {
+		return nil, err
+	}
+
+	typeSwitch := isTypeSwitchGuard(tag)
+	body, err := p.eatCaseBlock(func() (goast.Stmt, error) {
+		return p.eatCaseClause(typeSwitch)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if typeSwitch {
+		return &goast.TypeSwitchStmt{Switch: keyword.Pos, Init: init, Assign: tag, Body: body}, nil
+	}
+	stmt := &goast.SwitchStmt{Switch: keyword.Pos, Init: init, Body: body}
+	if tag != nil {
+		x, ok := tag.(*goast.ExprStmt)
+		if !ok {
+			token, _ := p.currentToken()
+			return nil, p.errorf(token, UnexpectedToken, "switch expression must be an expression")
+		}
+		stmt.Tag = x.X
+	}
+	return stmt, nil
+}
+
+// https://golang.org/ref/spec#TypeSwitchGuard
+func isTypeSwitchGuard(stmt goast.Stmt) bool {
+	var x goast.Expr
+	switch s := stmt.(type) {
+	case *goast.ExprStmt:
+		x = s.X
+	case *goast.AssignStmt:
+		if s.Tok != gotoken.DEFINE || len(s.Lhs) != 1 || len(s.Rhs) != 1 {
+			return false
+		}
+		x = s.Rhs[0]
+	default:
+		return false
+	}
+	assert, ok := x.(*goast.TypeAssertExpr)
+	return ok && assert.Type == nil
+}
+
+// eatCaseBlock parses the braces enclosing the clauses of switch and select
+// statements.
+func (p *Parser) eatCaseBlock(eatClause func() (goast.Stmt, error)) (*goast.BlockStmt, error) {
+	lbrace, err := p.eat(gotoken.LBRACE)
+	if err != nil {
+		return nil, err
+	}
+	body := &goast.BlockStmt{Lbrace: lbrace.Pos}
+	for {
+		if rbrace, err := p.eat(gotoken.RBRACE); err == nil {
+			body.Rbrace = rbrace.Pos
+			return body, nil
+		}
+		clause, err := eatClause()
+		if err != nil {
+			return nil, err
+		}
+		body.List = append(body.List, clause)
+	}
+}
+
+// https://golang.org/ref/spec#ExprCaseClause
+// https://golang.org/ref/spec#TypeCaseClause
+func (p *Parser) eatCaseClause(typeSwitch bool) (goast.Stmt, error) {
+	keyword, err := p.currentToken()
+	if err != nil {
+		return nil, err
+	}
+	clause := &goast.CaseClause{Case: keyword.Pos}
+	switch keyword.Type {
+	case gotoken.CASE:
+		p.next()
+		if typeSwitch {
+			clause.List, err = p.eatTypeList()
+		} else {
+			clause.List, err = p.eatExpressionList()
+		}
+		if err != nil {
+			return nil, err
+		}
+	case gotoken.DEFAULT:
+		p.next()
+	default:
+		return nil, p.errorf(keyword, UnexpectedToken, "expected 'case' or 'default', found %s", describeToken(keyword))
+	}
+	colon, err := p.eat(gotoken.COLON)
+	if err != nil {
+		return nil, err
+	}
+	clause.Colon = colon.Pos
+	clause.Body = p.eatStatementList()
+	return clause, nil
+}
+
+// https://golang.org/ref/spec#Select_statements
+func (p *Parser) eatSelectStmt() (goast.Stmt, error) {
+	keyword, err := p.eat(gotoken.SELECT)
+	if err != nil {
+		return nil, err
+	}
+	body, err := p.eatCaseBlock(p.eatCommClause)
+	if err != nil {
+		return nil, err
+	}
+	return &goast.SelectStmt{Select: keyword.Pos, Body: body}, nil
+}
+
+// https://golang.org/ref/spec#CommClause
+func (p *Parser) eatCommClause() (goast.Stmt, error) {
+	keyword, err := p.currentToken()
+	if err != nil {
+		return nil, err
+	}
+	clause := &goast.CommClause{Case: keyword.Pos}
+	switch keyword.Type {
+	case gotoken.CASE:
+		p.next()
+		if clause.Comm, err = p.eatSimpleStmt(basic); err != nil {
+			return nil, err
+		}
+	case gotoken.DEFAULT:
+		p.next()
+	default:
+		return nil, p.errorf(keyword, UnexpectedToken, "expected 'case' or 'default', found %s", describeToken(keyword))
+	}
+	colon, err := p.eat(gotoken.COLON)
+	if err != nil {
+		return nil, err
+	}
+	clause.Colon = colon.Pos
+	clause.Body = p.eatStatementList()
+	return clause, nil
+}
+
+// https://golang.org/ref/spec#For_statements
+func (p *Parser) eatForStmt() (goast.Stmt, error) {
+	keyword, err := p.eat(gotoken.FOR)
+	if err != nil {
+		return nil, err
+	}
+
+	var init, cond, post goast.Stmt
+	prevLev := p.exprLev
+	p.exprLev = -1
+	err = func() error {
+		if _, err := p.try(gotoken.LBRACE); err == nil {
+			return nil
+		}
+		if _, err := p.try(gotoken.SEMICOLON); err != nil {
+			if cond, err = p.eatSimpleStmt(rangeOk); err != nil {
+				return err
+			}
+		}
+		if isRangeClause(cond) {
+			return nil
+		}
+		if _, err := p.eat(gotoken.SEMICOLON); err != nil {
+			return nil
+		}
+		// https://golang.org/ref/spec#ForClause
+		init, cond = cond, nil
+		if _, err := p.try(gotoken.SEMICOLON); err != nil {
+			if cond, err = p.eatSimpleStmt(basic); err != nil {
+				return err
+			}
+		}
+		if _, err := p.eat(gotoken.SEMICOLON); err != nil {
+			return err
+		}
+		if _, err := p.try(gotoken.LBRACE); err != nil {
+			if post, err = p.eatSimpleStmt(basic); err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+	p.exprLev = prevLev
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := p.eatBlockStmt()
+	if err != nil {
+		return nil, err
+	}
+
+	if isRangeClause(cond) {
+		// https://golang.org/ref/spec#RangeClause
+		assign := cond.(*goast.AssignStmt)
+		stmt := &goast.RangeStmt{
+			For:    keyword.Pos,
+			TokPos: assign.TokPos,
+			Tok:    assign.Tok,
+			X:      assign.Rhs[0].(*goast.UnaryExpr).X,
+			Range:  assign.Rhs[0].Pos(),
+			Body:   body,
+		}
+		switch len(assign.Lhs) {
+		case 0:
+			// for range x
+		case 2:
+			stmt.Value = assign.Lhs[1]
+			fallthrough
+		case 1:
+			stmt.Key = assign.Lhs[0]
+		default:
+			return nil, p.errorf(keyword, UnexpectedToken, "range clause permits at most two iteration variables")
+		}
+		return stmt, nil
+	}
+
+	stmt := &goast.ForStmt{For: keyword.Pos, Init: init, Post: post, Body: body}
+	if cond != nil {
+		x, ok := cond.(*goast.ExprStmt)
+		if !ok {
+			return nil, p.errorf(keyword, MissingCondition, "expected for loop condition")
+		}
+		stmt.Cond = x.X
+	}
+	return stmt, nil
+}
+
+func isRangeClause(stmt goast.Stmt) bool {
+	assign, ok := stmt.(*goast.AssignStmt)
+	if !ok || len(assign.Rhs) != 1 {
+		return false
+	}
+	x, ok := assign.Rhs[0].(*goast.UnaryExpr)
+	return ok && x.Op == gotoken.RANGE
+}
@@ -0,0 +1,449 @@
+package parser
+
+import (
+	goast "go/ast"
+	gotoken "go/token"
+
+	"holang/pkg/lexer"
+	hotoken "holang/pkg/token"
+)
+
+// https://golang.org/ref/spec#TypeList
+func (p *Parser) eatTypeList() ([]goast.Expr, error) {
+	typ, err := p.eatType()
+	if err != nil {
+		return nil, err
+	}
+	types := []goast.Expr{typ}
+	for {
+		if _, err := p.eat(gotoken.COMMA); err != nil {
+			return types, nil
+		}
+		typ, err := p.eatType()
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, typ)
+	}
+}
+
+// https://golang.org/ref/spec#Types
+func (p *Parser) eatType() (goast.Expr, error) {
+	token, err := p.currentToken()
+	if err != nil {
+		return nil, err
+	}
+	switch token.Type {
+	case gotoken.IDENT:
+		return p.eatTypeName()
+	case gotoken.LBRACK:
+		return p.eatArrayOrSliceType()
+	case gotoken.STRUCT:
+		return p.eatStructType()
+	case gotoken.MUL:
+		return p.eatPointerType()
+	case hotoken.POW:
+		// A pointer to a pointer
+		p.splitPow()
+		return p.eatPointerType()
+	case gotoken.FUNC:
+		return p.eatFuncType()
+	case gotoken.INTERFACE:
+		return p.eatInterfaceType()
+	case gotoken.MAP:
+		return p.eatMapType()
+	case gotoken.CHAN, gotoken.ARROW:
+		return p.eatChanType()
+	case gotoken.LPAREN:
+		p.next()
+		typ, err := p.eatType()
+		if err != nil {
+			return nil, err
+		}
+		rparen, err := p.eat(gotoken.RPAREN)
+		if err != nil {
+			return nil, err
+		}
+		return &goast.ParenExpr{Lparen: token.Pos, X: typ, Rparen: rparen.Pos}, nil
+	}
+	return nil, p.errorf(token, UnexpectedToken, "expected type, found %s", describeToken(token))
+}
+
+// startsType reports whether the current token can start a type.
+func (p *Parser) startsType() bool {
+	token, err := p.currentToken()
+	if err != nil {
+		return false
+	}
+	switch token.Type {
+	case gotoken.IDENT, gotoken.LBRACK, gotoken.STRUCT, gotoken.MUL, hotoken.POW, gotoken.FUNC,
+		gotoken.INTERFACE, gotoken.MAP, gotoken.CHAN, gotoken.ARROW, gotoken.LPAREN:
+		return true
+	}
+	return false
+}
+
+// https://golang.org/ref/spec#TypeName
+func (p *Parser) eatTypeName() (goast.Expr, error) {
+	ident, err := p.eatIdent()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.eat(gotoken.PERIOD); err != nil {
+		return ident, nil
+	}
+	sel, err := p.eatIdent()
+	if err != nil {
+		return nil, err
+	}
+	return &goast.SelectorExpr{X: ident, Sel: sel}, nil
+}
+
+// https://golang.org/ref/spec#Array_types
+// https://golang.org/ref/spec#Slice_types
+func (p *Parser) eatArrayOrSliceType() (goast.Expr, error) {
+	lbrack, err := p.eat(gotoken.LBRACK)
+	if err != nil {
+		return nil, err
+	}
+	typ := &goast.ArrayType{Lbrack: lbrack.Pos}
+	if _, err := p.eat(gotoken.RBRACK); err != nil {
+		if ellipsis, err := p.eat(gotoken.ELLIPSIS); err == nil {
+			// Only valid in composite literals, which are checked later on
+			typ.Len = &goast.Ellipsis{Ellipsis: ellipsis.Pos}
+		} else if typ.Len, err = p.eatExpression(); err != nil {
+			return nil, err
+		}
+		if _, err := p.eat(gotoken.RBRACK); err != nil {
+			return nil, err
+		}
+	}
+	elt, err := p.eatType()
+	if err != nil {
+		return nil, err
+	}
+	typ.Elt = elt
+	return typ, nil
+}
+
+// https://golang.org/ref/spec#Struct_types
+func (p *Parser) eatStructType() (goast.Expr, error) {
+	keyword, err := p.eat(gotoken.STRUCT)
+	if err != nil {
+		return nil, err
+	}
+	lbrace, err := p.eat(gotoken.LBRACE)
+	if err != nil {
+		return nil, err
+	}
+	fields := &goast.FieldList{Opening: lbrace.Pos}
+	for {
+		if rbrace, err := p.eat(gotoken.RBRACE); err == nil {
+			fields.Closing = rbrace.Pos
+			return &goast.StructType{Struct: keyword.Pos, Fields: fields}, nil
+		}
+		first, err := p.currentToken()
+		if err != nil {
+			return nil, err
+		}
+		field, err := p.eatFieldDecl()
+		if err != nil {
+			return nil, err
+		}
+		field.Doc, field.Comment = p.leadComment(first), p.lineComment()
+		fields.List = append(fields.List, field)
+		// The semicolon may be omitted before the closing brace
+		if _, err := p.try(gotoken.RBRACE); err == nil {
+			continue
+		}
+		if _, err := p.eat(gotoken.SEMICOLON); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// https://golang.org/ref/spec#FieldDecl
+func (p *Parser) eatFieldDecl() (*goast.Field, error) {
+	field := &goast.Field{}
+	token, err := p.currentToken()
+	if err != nil {
+		return nil, err
+	}
+	next, _ := p.peek(1)
+	switch {
+	case token.Type == gotoken.MUL:
+		// https://golang.org/ref/spec#EmbeddedField
+		star := token
+		p.next()
+		typ, err := p.eatTypeName()
+		if err != nil {
+			return nil, err
+		}
+		field.Type = &goast.StarExpr{Star: star.Pos, X: typ}
+	case token.Type == gotoken.IDENT && (next.Type == gotoken.PERIOD ||
+		next.Type == gotoken.SEMICOLON || next.Type == gotoken.RBRACE || next.Type == gotoken.STRING):
+		typ, err := p.eatTypeName()
+		if err != nil {
+			return nil, err
+		}
+		field.Type = typ
+	default:
+		names, err := p.eatIdentifierList()
+		if err != nil {
+			return nil, err
+		}
+		field.Names = names
+		typ, err := p.eatType()
+		if err != nil {
+			return nil, err
+		}
+		field.Type = typ
+	}
+	if tag, err := p.eat(gotoken.STRING); err == nil {
+		field.Tag = &goast.BasicLit{ValuePos: tag.Pos, Kind: tag.Type, Value: tag.Value}
+	}
+	return field, nil
+}
+
+// https://golang.org/ref/spec#Pointer_types
+func (p *Parser) eatPointerType() (goast.Expr, error) {
+	star, err := p.eat(gotoken.MUL)
+	if err != nil {
+		return nil, err
+	}
+	typ, err := p.eatType()
+	if err != nil {
+		return nil, err
+	}
+	return &goast.StarExpr{Star: star.Pos, X: typ}, nil
+}
+
+// https://golang.org/ref/spec#Function_types
+func (p *Parser) eatFuncType() (*goast.FuncType, error) {
+	keyword, err := p.eat(gotoken.FUNC)
+	if err != nil {
+		return nil, err
+	}
+	typ, err := p.eatSignature()
+	if err != nil {
+		return nil, err
+	}
+	typ.Func = keyword.Pos
+	return typ, nil
+}
+
+// https://golang.org/ref/spec#Signature
+func (p *Parser) eatSignature() (*goast.FuncType, error) {
+	params, err := p.eatParameters()
+	if err != nil {
+		return nil, err
+	}
+	typ := &goast.FuncType{Params: params}
+	// https://golang.org/ref/spec#Result
+	if _, err := p.try(gotoken.LPAREN); err == nil {
+		results, err := p.eatParameters()
+		if err != nil {
+			return nil, err
+		}
+		typ.Results = results
+	} else if p.startsType() {
+		result, err := p.eatType()
+		if err != nil {
+			return nil, err
+		}
+		typ.Results = &goast.FieldList{List: []*goast.Field{{Type: result}}}
+	}
+	return typ, nil
+}
+
+// https://golang.org/ref/spec#Parameters
+//
+// Whether a parameter list declares names can only be told once a type
+// follows its first entries: `(a, b int)` declares a and b, while `(a, b)`
+// lists two types.
+func (p *Parser) eatParameters() (*goast.FieldList, error) {
+	lparen, err := p.eat(gotoken.LPAREN)
+	if err != nil {
+		return nil, err
+	}
+	params := &goast.FieldList{Opening: lparen.Pos}
+
+	entries := []goast.Expr{}
+	starts := []lexer.Token{}
+	for {
+		if _, err := p.try(gotoken.RPAREN); err == nil {
+			break
+		}
+		start, _ := p.currentToken()
+		entry, err := p.eatParameterType()
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+		starts = append(starts, start)
+		if _, err := p.eat(gotoken.COMMA); err != nil {
+			break
+		}
+	}
+
+	if _, err := p.try(gotoken.RPAREN); err == nil || len(entries) == 0 {
+		for _, entry := range entries {
+			params.List = append(params.List, &goast.Field{Type: entry})
+		}
+	} else {
+		// The entries read so far were the names of the first group
+		names := []*goast.Ident{}
+		for i, entry := range entries {
+			name, ok := entry.(*goast.Ident)
+			if !ok {
+				return nil, p.errorf(starts[i], UnexpectedToken, "mixed named and unnamed parameters")
+			}
+			names = append(names, name)
+		}
+		for {
+			typ, err := p.eatParameterType()
+			if err != nil {
+				return nil, err
+			}
+			params.List = append(params.List, &goast.Field{Names: names, Type: typ})
+			if _, err := p.eat(gotoken.COMMA); err != nil {
+				break
+			}
+			if _, err := p.try(gotoken.RPAREN); err == nil {
+				break
+			}
+			if names, err = p.eatIdentifierList(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	rparen, err := p.eat(gotoken.RPAREN)
+	if err != nil {
+		return nil, err
+	}
+	params.Closing = rparen.Pos
+	return params, nil
+}
+
+// eatParameterType parses the type of a parameter, which may be variadic.
+func (p *Parser) eatParameterType() (goast.Expr, error) {
+	if ellipsis, err := p.eat(gotoken.ELLIPSIS); err == nil {
+		elt, err := p.eatType()
+		if err != nil {
+			return nil, err
+		}
+		return &goast.Ellipsis{Ellipsis: ellipsis.Pos, Elt: elt}, nil
+	}
+	return p.eatType()
+}
+
+// https://golang.org/ref/spec#Interface_types
+func (p *Parser) eatInterfaceType() (goast.Expr, error) {
+	keyword, err := p.eat(gotoken.INTERFACE)
+	if err != nil {
+		return nil, err
+	}
+	lbrace, err := p.eat(gotoken.LBRACE)
+	if err != nil {
+		return nil, err
+	}
+	methods := &goast.FieldList{Opening: lbrace.Pos}
+	for {
+		if rbrace, err := p.eat(gotoken.RBRACE); err == nil {
+			methods.Closing = rbrace.Pos
+			return &goast.InterfaceType{Interface: keyword.Pos, Methods: methods}, nil
+		}
+		first, err := p.currentToken()
+		if err != nil {
+			return nil, err
+		}
+		method, err := p.eatMethodSpec()
+		if err != nil {
+			return nil, err
+		}
+		method.Doc, method.Comment = p.leadComment(first), p.lineComment()
+		methods.List = append(methods.List, method)
+		// The semicolon may be omitted before the closing brace
+		if _, err := p.try(gotoken.RBRACE); err == nil {
+			continue
+		}
+		if _, err := p.eat(gotoken.SEMICOLON); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// https://golang.org/ref/spec#MethodSpec
+func (p *Parser) eatMethodSpec() (*goast.Field, error) {
+	if next, _ := p.peek(1); next.Type != gotoken.LPAREN {
+		// https://golang.org/ref/spec#InterfaceTypeName
+		typ, err := p.eatTypeName()
+		if err != nil {
+			return nil, err
+		}
+		return &goast.Field{Type: typ}, nil
+	}
+	name, err := p.eatIdent()
+	if err != nil {
+		return nil, err
+	}
+	typ, err := p.eatSignature()
+	if err != nil {
+		return nil, err
+	}
+	return &goast.Field{Names: []*goast.Ident{name}, Type: typ}, nil
+}
+
+// https://golang.org/ref/spec#Map_types
+func (p *Parser) eatMapType() (goast.Expr, error) {
+	keyword, err := p.eat(gotoken.MAP)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.eat(gotoken.LBRACK); err != nil {
+		return nil, err
+	}
+	key, err := p.eatType()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.eat(gotoken.RBRACK); err != nil {
+		return nil, err
+	}
+	value, err := p.eatType()
+	if err != nil {
+		return nil, err
+	}
+	return &goast.MapType{Map: keyword.Pos, Key: key, Value: value}, nil
+}
+
+// https://golang.org/ref/spec#Channel_types
+func (p *Parser) eatChanType() (goast.Expr, error) {
+	typ := &goast.ChanType{Dir: goast.SEND | goast.RECV}
+	if arrow, err := p.eat(gotoken.ARROW); err == nil {
+		typ.Begin = arrow.Pos
+		typ.Arrow = arrow.Pos
+		typ.Dir = goast.RECV
+		if _, err := p.try(gotoken.CHAN); err != nil {
+			return nil, err
+		}
+	}
+	keyword, err := p.eat(gotoken.CHAN)
+	if err != nil {
+		return nil, err
+	}
+	if typ.Dir == goast.SEND|goast.RECV {
+		typ.Begin = keyword.Pos
+		if arrow, err := p.eat(gotoken.ARROW); err == nil {
+			typ.Arrow = arrow.Pos
+			typ.Dir = goast.SEND
+		}
+	}
+	value, err := p.eatType()
+	if err != nil {
+		return nil, err
+	}
+	typ.Value = value
+	return typ, nil
+}
@@ -0,0 +1,126 @@
+package printer
+
+import (
+	"bytes"
+	"fmt"
+	goast "go/ast"
+	"go/scanner"
+	gotoken "go/token"
+)
+
+// autogenerated is the file of the generated declarations in the //line
+// directives, when the Go file has no name. The go runtime uses it for the
+// code generated by the compiler.
+const autogenerated = "<autogenerated>"
+
+// addLineDirectives inserts //line directives in src before the lines
+// whose position in the holang source, given by lines and columns, does not
+// follow from the previous directive, and returns the new source and map.
+//
+// A directive gives the position of the first character of the next line:
+// its column is chosen for the first token of the line to get the column of
+// its source when it can, the other lines keeping the columns of the Go
+// source.
+func (c Config) addLineDirectives(src []byte, lines LineMap, columns map[int]int) ([]byte, LineMap) {
+	starts := tokenColumns(src)
+	out := &bytes.Buffer{}
+	outLines := LineMap{}
+	expected := gotoken.Position{} // position of the next line, as given by the directives
+	for i, line := range bytes.SplitAfter(src, []byte("\n")) {
+		if len(line) == 0 {
+			break
+		}
+		pos := lines[i]
+		column, starts := starts[i+1]
+		switch {
+		case !starts:
+			// Inside a token spanning several lines
+		case pos.IsValid():
+			name := c.SourceName
+			if name == "" {
+				name = pos.Filename
+			}
+			pos.Column = columns[pos.Line]
+			// A directive cannot move a token to the left of its column
+			// in the Go source, deeper nested by lowering
+			if expected.Filename != name || expected.Line != pos.Line || pos.Column > column {
+				col := 1
+				if pos.Column > column {
+					col = pos.Column - column + 1
+				}
+				fmt.Fprintf(out, "//line %s:%d:%d\n", name, pos.Line, col)
+				outLines = append(outLines, gotoken.Position{})
+				expected = gotoken.Position{Filename: name, Line: pos.Line}
+			}
+		case expected.Filename != "" && expected.Filename != c.filename():
+			// Back to the Go source for the generated declarations
+			fmt.Fprintf(out, "//line %s:%d:1\n", c.filename(), len(outLines)+2)
+			outLines = append(outLines, gotoken.Position{})
+			expected = gotoken.Position{Filename: c.filename(), Line: len(outLines) + 1}
+		}
+		out.Write(line)
+		outLines = append(outLines, pos)
+		expected.Line++
+	}
+	return out.Bytes(), outLines
+}
+
+func (c Config) filename() string {
+	if c.Filename == "" {
+		return autogenerated
+	}
+	return c.Filename
+}
+
+// tokenColumns returns the column of the first token starting on each line
+// of src, by line. Lines without any are inside tokens spanning several
+// lines, or blank.
+func tokenColumns(src []byte) map[int]int {
+	fset := gotoken.NewFileSet()
+	file := fset.AddFile("", -1, len(src))
+	var s scanner.Scanner
+	s.Init(file, src, nil, scanner.ScanComments)
+	columns := map[int]int{}
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == gotoken.EOF {
+			return columns
+		}
+		if tok == gotoken.SEMICOLON && lit == "\n" {
+			continue
+		}
+		p := file.Position(pos)
+		if _, ok := columns[p.Line]; !ok {
+			columns[p.Line] = p.Column
+		}
+	}
+}
+
+// sourceColumns returns the column of the first token of each line of the
+// source of file, by line, as far as its nodes and comments tell.
+func sourceColumns(fset *gotoken.FileSet, file *goast.File) map[int]int {
+	columns := map[int]int{}
+	add := func(pos gotoken.Pos) {
+		if !pos.IsValid() {
+			return
+		}
+		p := fset.Position(pos)
+		if c, ok := columns[p.Line]; !ok || p.Column < c {
+			columns[p.Line] = p.Column
+		}
+	}
+	goast.Inspect(file, func(node goast.Node) bool {
+		if node != nil && node.Pos().IsValid() {
+			add(node.Pos())
+			// The end of a node is its closing bracket, if any
+			add(node.End() - 1)
+		}
+		return true
+	})
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			add(comment.Pos())
+		}
+	}
+	return columns
+}
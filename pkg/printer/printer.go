@@ -1,12 +1,146 @@
 package printer
 
 import (
+	"bufio"
+	"bytes"
 	goast "go/ast"
+	"go/format"
+	goprinter "go/printer"
+	gotoken "go/token"
 	"io"
-
-	"github.com/davecgh/go-spew/spew"
+	"reflect"
+	"strconv"
+	"strings"
 )
 
-func FprintFile(w io.Writer, file goast.File) {
-	spew.Fdump(w, file)
+// Config controls the Go source printed.
+type Config struct {
+	// LineDirectives makes the source carry //line directives, so that the
+	// positions in the Go source refer to the holang source for the go
+	// command, stack traces and debuggers.
+	LineDirectives bool
+	// SourceName is the name of the holang file in the directives, the
+	// filename of its positions by default. A relative name is interpreted
+	// relative to the directory of the Go file.
+	SourceName string
+	// Filename is the name of the Go file, to which the directives return
+	// for the declarations generated by lowering; "<autogenerated>" by
+	// default.
+	Filename string
+}
+
+// FprintFile writes file to w as gofmt-formatted Go source, along with the
+// comments it holds.
+func FprintFile(w io.Writer, fset *gotoken.FileSet, file goast.File) error {
+	src, _, err := Source(fset, file)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// A LineMap maps the lines of printed Go source to the lines of the holang
+// source they come from: line n of the Go source comes from LineMap[n-1],
+// the zero Position for the declarations generated by lowering.
+type LineMap []gotoken.Position
+
+// Source returns file as gofmt-formatted Go source, along with the map of
+// its lines. It uses the zero Config.
+func Source(fset *gotoken.FileSet, file goast.File) ([]byte, LineMap, error) {
+	return Config{}.Source(fset, file)
+}
+
+// Source returns file as gofmt-formatted Go source, along with the map of
+// its lines.
+//
+// The declarations generated by lowering holang constructs have no position,
+// while comments are placed according to theirs: printed amid the source
+// declarations, they would take the comments following them. They are
+// printed after the declarations of the source instead.
+func (c Config) Source(fset *gotoken.FileSet, file goast.File) ([]byte, LineMap, error) {
+	source, generated := []goast.Decl{}, []goast.Decl{}
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*goast.GenDecl); decl.Pos().IsValid() || ok && gen.Tok == gotoken.IMPORT {
+			source = append(source, decl)
+		} else {
+			generated = append(generated, decl)
+		}
+	}
+	file.Decls = source
+
+	// Printed with //line directives wherever the lines of the output and
+	// of the source differ, which give the map once stripped
+	config := goprinter.Config{Mode: goprinter.UseSpaces | goprinter.TabIndent | goprinter.SourcePos, Tabwidth: 8}
+	buf := &bytes.Buffer{}
+	if err := config.Fprint(buf, fset, &file); err != nil {
+		return nil, nil, err
+	}
+	src, lines := stripLineDirectives(buf.Bytes())
+	buf = bytes.NewBuffer(src)
+	for _, decl := range generated {
+		buf.WriteString("\n")
+		// Printed on their own, without the comments of the file, which their
+		// missing positions would misplace
+		if err := format.Node(buf, fset, decl); err != nil {
+			return nil, nil, err
+		}
+		buf.WriteString("\n")
+	}
+	src = buf.Bytes()
+	lines = append(lines, make(LineMap, bytes.Count(src, []byte("\n"))-len(lines))...)
+
+	// Finish the job of gofmt: sort imports and normalize number literals
+	formatted, err := format.Source(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	if bytes.Count(formatted, []byte("\n")) != len(lines) {
+		// Duplicate imports were removed: the lines cannot be told anymore
+		lines = make(LineMap, bytes.Count(formatted, []byte("\n")))
+	}
+	if c.LineDirectives {
+		formatted, lines = c.addLineDirectives(formatted, lines, sourceColumns(fset, &file))
+	}
+	return formatted, lines, nil
+}
+
+// stripLineDirectives removes the //line directives from src, returning the
+// map of the lines they describe.
+func stripLineDirectives(src []byte) ([]byte, LineMap) {
+	out := &bytes.Buffer{}
+	lines := LineMap{}
+	pos := gotoken.Position{}
+	scanner := bufio.NewScanner(bytes.NewReader(src))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "//line ") {
+			text := strings.TrimPrefix(line, "//line ")
+			if i := strings.LastIndex(text, ":"); i >= 0 {
+				if n, err := strconv.Atoi(text[i+1:]); err == nil {
+					pos = gotoken.Position{Filename: text[:i], Line: n}
+					continue
+				}
+			}
+		}
+		lines = append(lines, pos)
+		if pos.IsValid() {
+			pos.Line++
+		}
+		out.WriteString(line)
+		out.WriteString("\n")
+	}
+	return out.Bytes(), lines
+}
+
+// dumpFilter leaves out the fields holding nil or zero values, for the
+// dumps not to depend on the fields the go/ast of the Go release has.
+func dumpFilter(name string, v reflect.Value) bool {
+	return goast.NotNilFilter(name, v) && !v.IsZero()
+}
+
+// DumpFile writes the internal representation of file to w, for debugging.
+// Positions are given by their offsets in the file set.
+func DumpFile(w io.Writer, file goast.File) error {
+	return goast.Fprint(w, nil, &file, dumpFilter)
 }
@@ -0,0 +1,90 @@
+package printer_test
+
+import (
+	"bytes"
+	"fmt"
+	goast "go/ast"
+	goparser "go/parser"
+	gotoken "go/token"
+	"strings"
+	"testing"
+
+	"holang/pkg/lexer"
+	"holang/pkg/lower"
+	"holang/pkg/parser"
+	"holang/pkg/printer"
+)
+
+const src = `package main
+
+import "fmt"
+
+func main() {
+	n := 3
+	fmt.Println(match n { 3 => n ** 2, _ => 0 },
+		square(n))
+}
+
+func square(k int) int { return k ** 2 }
+`
+
+// TestLineDirectives checks that the positions of the Go source printed
+// with //line directives refer to the holang source, but for the helpers of
+// the lowered code which refer to the Go file.
+func TestLineDirectives(t *testing.T) {
+	fset := gotoken.NewFileSet()
+	p := parser.NewParser(lexer.NewLexer(fset, strings.NewReader(src), "/src/main.ho"))
+	file, err := p.ParseFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	helpers, err := lower.Package(fset, []*goast.File{&file}, p.Constructs(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Decls = append(file.Decls, helpers.Decls...)
+	config := printer.Config{LineDirectives: true, SourceName: "main.ho", Filename: "main.ho.go"}
+	gen, lines, err := config.Source(fset, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(gen, []byte("\n")); len(lines) != n {
+		t.Errorf("got a map of %d lines for %d lines", len(lines), n)
+	}
+
+	goFset := gotoken.NewFileSet()
+	goFile, err := goparser.ParseFile(goFset, "main.ho.go", gen, 0)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, gen)
+	}
+	// Lines alone for the identifiers moved by lowering
+	want := []string{"1:9", "5:6", "6:2", "7:2", "7:6", "7", "7", "8:3", "8:10", "11:6", "11:13", "11"}
+	got := []string{}
+	names := map[string]bool{"main": true, "n": true, "fmt": true, "Println": true, "square": true, "k": true}
+	goast.Inspect(goFile, func(node goast.Node) bool {
+		id, ok := node.(*goast.Ident)
+		if !ok || !names[id.Name] {
+			return true
+		}
+		pos := goFset.PositionFor(id.Pos(), true)
+		if pos.Filename != "main.ho" {
+			t.Errorf("%s at %s, want main.ho", id.Name, pos)
+		}
+		if len(got) < len(want) && !strings.Contains(want[len(got)], ":") {
+			got = append(got, fmt.Sprint(pos.Line))
+		} else {
+			got = append(got, fmt.Sprintf("%d:%d", pos.Line, pos.Column))
+		}
+		return true
+	})
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("got positions %v, want %v\n%s", got, want, gen)
+	}
+	for _, decl := range goFile.Decls {
+		if f, ok := decl.(*goast.FuncDecl); ok && f.Name.Name == "holangPowUint" {
+			if pos := goFset.PositionFor(f.Pos(), true); pos.Filename != "main.ho.go" || pos.Line != goFset.PositionFor(f.Pos(), false).Line {
+				t.Errorf("helper at %s, want its position in main.ho.go", pos)
+			}
+		}
+	}
+}
@@ -10,6 +10,7 @@ const (
 	// Operators
 	POW
 	POW_ASSIGN
+	FAT_ARROW
 
 	// Keywords
 	ENUM
@@ -17,3 +18,20 @@ const (
 
 	holang_end
 )
+
+var tokens = map[Token]string{
+	POW:        "**",
+	POW_ASSIGN: "**=",
+	FAT_ARROW:  "=>",
+	ENUM:       "enum",
+	MATCH:      "match",
+}
+
+// String returns the string corresponding to the token, holang tokens
+// included.
+func String(t Token) string {
+	if s, ok := tokens[t]; ok {
+		return s
+	}
+	return t.String()
+}
@@ -0,0 +1,189 @@
+package samples_test
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	gotoken "go/token"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"holang/pkg/diagnostics"
+	"holang/pkg/driver"
+	"holang/pkg/lexer"
+	"holang/pkg/printer"
+	hotoken "holang/pkg/token"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files with the actual outputs")
+
+// golden is an output checked against a file of testdata.
+type golden struct {
+	ext string // extension of the golden file
+	out func(name string, src []byte) []byte
+}
+
+var goldens = []golden{
+	{".tokens", tokens},
+	{".ast", tree},
+	{".go.golden", translation},
+}
+
+// TestGolden checks the token stream, the syntax tree and the Go translation
+// of every sample against the golden files of testdata, named after the
+// sample without its extension. Run with -update to rewrite them.
+func TestGolden(t *testing.T) {
+	names, err := samples()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) == 0 {
+		t.Fatal("no samples")
+	}
+	for _, name := range names {
+		src, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Run(name, func(t *testing.T) {
+			for _, g := range goldens {
+				path := filepath.Join("testdata", strings.TrimSuffix(name, filepath.Ext(name))+g.ext)
+				t.Run(strings.TrimPrefix(g.ext, "."), func(t *testing.T) {
+					check(t, path, g.out(name, src))
+				})
+			}
+		})
+	}
+}
+
+// samples returns the names of the samples: the holang files and the Go
+// files, tests aside, holang being a superset of Go.
+func samples() ([]string, error) {
+	hos, err := filepath.Glob("*" + driver.Ext)
+	if err != nil {
+		return nil, err
+	}
+	gos, err := filepath.Glob("*.go")
+	if err != nil {
+		return nil, err
+	}
+	names := hos
+	for _, name := range gos {
+		if !strings.HasSuffix(name, "_test.go") {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// check compares got with the golden file at path, or rewrites the file
+// with -update.
+func check(t *testing.T, path string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := ioutil.WriteFile(path, got, 0666); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs (run with -update to rewrite it):\n%s", path, diff(string(want), string(got)))
+	}
+}
+
+// tokens returns the token stream of src as printed by ho tokens, followed
+// by the lexer errors.
+func tokens(name string, src []byte) []byte {
+	toks, err := lexer.Tokenize(nil, bytes.NewReader(src), name)
+	var out bytes.Buffer
+	for _, t := range toks {
+		fmt.Fprintf(&out, "%s\t%s\t%q\n", t.Position, hotoken.String(t.Type), t.Value)
+	}
+	list, _ := err.(diagnostics.List)
+	render(&out, name, src, list)
+	return out.Bytes()
+}
+
+// tree returns the dump of the syntax tree of src, followed by the syntax
+// errors.
+func tree(name string, src []byte) []byte {
+	file, _, diags := driver.Parse(gotoken.NewFileSet(), name, src)
+	var out bytes.Buffer
+	if err := printer.DumpFile(&out, file); err != nil {
+		fmt.Fprintln(&out, err)
+	}
+	render(&out, name, src, diags)
+	return out.Bytes()
+}
+
+// translation returns the Go translation of src, or its errors if it has
+// any.
+func translation(name string, src []byte) []byte {
+	gen, _, diags := driver.Translate(name, src, printer.Config{}, driver.NewImporter())
+	if diags.Err() == nil {
+		return gen
+	}
+	var out bytes.Buffer
+	render(&out, name, src, diags)
+	return out.Bytes()
+}
+
+func render(out *bytes.Buffer, name string, src []byte, diags diagnostics.List) {
+	if len(diags) == 0 {
+		return
+	}
+	out.WriteString("\n")
+	r := diagnostics.Renderer{Sources: map[string][]byte{name: src}}
+	r.RenderAll(out, diags)
+}
+
+// diff returns the lines of want and got that differ, prefixed with - and +,
+// along with the line numbers of want.
+func diff(want, got string) string {
+	a, b := strings.SplitAfter(want, "\n"), strings.SplitAfter(got, "\n")
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var out strings.Builder
+	line := func(prefix string, i int, s string) {
+		fmt.Fprintf(&out, "%4d %s %s", i+1, prefix, s)
+		if !strings.HasSuffix(s, "\n") {
+			out.WriteString("\n")
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			line("-", i, a[i])
+			i++
+		default:
+			line("+", i, b[j])
+			j++
+		}
+	}
+	return out.String()
+}